@@ -0,0 +1,78 @@
+package apnstest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+func TestClock(t *testing.T) {
+	now := time.Date(2021, 8, 15, 12, 0, 0, 0, time.UTC)
+	c := NewClock(now)
+	if !c.Now().Equal(now) {
+		t.Errorf("Now: %v; want: %v", c.Now(), now)
+	}
+
+	c.Advance(time.Hour)
+	if !c.Now().Equal(now.Add(time.Hour)) {
+		t.Errorf("Now: %v; want: %v", c.Now(), now.Add(time.Hour))
+	}
+
+	c.Set(now)
+	if !c.Now().Equal(now) {
+		t.Errorf("Now: %v; want: %v", c.Now(), now)
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	clientClock := NewClock(time.Now())
+	serverClock := NewClock(clientClock.Now())
+	client.Token.Clock = clientClock
+	s.Clock = serverClock
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	}
+
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != apns.Status200 {
+		t.Errorf("res.Status: %v; want: %v", res.Status, apns.Status200)
+	}
+	if p := s.Pushes()[0]; !p.Time.Equal(serverClock.Now()) {
+		t.Errorf("p.Time: %v; want: %v", p.Time, serverClock.Now())
+	}
+
+	// The client clock is behind, so the client believes the bearer is fresh,
+	// but it is expired for the server. The bearer is regenerated once by the client.
+	serverClock.Advance(2 * time.Hour)
+	res, err = client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != apns.ReasonExpiredProviderToken {
+		t.Errorf("res.Reason: %v; want: %v", res.Reason, apns.ReasonExpiredProviderToken)
+	}
+	if len(s.Pushes()) != 2 {
+		t.Errorf("pushes: %v; want: 2", len(s.Pushes()))
+	}
+
+	// The client clock catches up with the server.
+	clientClock.Advance(2 * time.Hour)
+	res, err = client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != apns.Status200 {
+		t.Errorf("res.Status: %v %v; want: %v", res.Status, res.Reason, apns.Status200)
+	}
+}
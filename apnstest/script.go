@@ -0,0 +1,220 @@
+package apnstest
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+// Action is a scripted behavior of Handler for a notification request.
+// Actions let tests reproduce APNs failures without a live endpoint.
+type Action struct {
+	// Status and Reason of the response instead of success.
+	// Applied only when the request passes the checks,
+	// otherwise the response reports the failed check.
+	// Zero Status keeps the checked response.
+	Status int
+	Reason string
+
+	// Timestamp for Status 410 response,
+	// represented in milliseconds since Epoch.
+	Timestamp int64
+
+	// Delay before the response is written.
+	Delay time.Duration
+
+	// GoAway sends GOAWAY frame with the response,
+	// so the client has to open a new connection for next requests.
+	GoAway bool
+
+	// Reset closes the TCP connection without a response by sending RST,
+	// so the client gets ECONNRESET ("connection reset by peer").
+	// The client gets unexpected EOF instead if it has not read
+	// all data sent before the reset, for example, on the first request of a connection.
+	Reset bool
+}
+
+// Unregistered returns Action answering Status 410 Unregistered
+// with the time at which the device token became inactive.
+func Unregistered(at time.Time) Action {
+	return Action{
+		Status:    apns.Status410,
+		Reason:    apns.ReasonUnregistered,
+		Timestamp: at.UnixNano() / int64(time.Millisecond),
+	}
+}
+
+// TooManyRequests returns Action answering Status 429 TooManyRequests.
+func TooManyRequests() Action {
+	return Action{Status: apns.Status429, Reason: apns.ReasonTooManyRequests}
+}
+
+// Shutdown returns Action answering Status 503 Shutdown.
+func Shutdown() Action {
+	return Action{Status: apns.Status503, Reason: apns.ReasonShutdown}
+}
+
+// GoAway returns Action sending GOAWAY frame with successful response.
+func GoAway() Action {
+	return Action{GoAway: true}
+}
+
+// Slow returns Action delaying successful response by d.
+func Slow(d time.Duration) Action {
+	return Action{Delay: d}
+}
+
+// ConnectionReset returns Action resetting the TCP connection without a response, see Action.Reset.
+func ConnectionReset() Action {
+	return Action{Reset: true}
+}
+
+// ScriptDevice queues actions for the next requests to deviceToken,
+// each request consumes one action in order.
+func (h *Handler) ScriptDevice(deviceToken string, actions ...Action) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.devices[deviceToken] = append(h.devices[deviceToken], actions...)
+}
+
+// ScriptRequest sets action for the n-th request received by the handler,
+// counting from 1 since creation or Reset.
+// Takes precedence over actions queued by ScriptDevice.
+func (h *Handler) ScriptRequest(n int, action Action) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests[n] = action
+}
+
+// Unregister makes every following request to deviceToken
+// answered with Status 410 Unregistered and timestamp at.
+func (h *Handler) Unregister(deviceToken string, at time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.unregistered[deviceToken] = at
+}
+
+// action returns scripted action for n-th request to deviceToken.
+func (h *Handler) action(n int, deviceToken string) (Action, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if a, ok := h.requests[n]; ok {
+		delete(h.requests, n)
+		return a, true
+	}
+	if q := h.devices[deviceToken]; len(q) > 0 {
+		h.devices[deviceToken] = q[1:]
+		return q[0], true
+	}
+	if at, ok := h.unregistered[deviceToken]; ok {
+		return Unregistered(at), true
+	}
+	return Action{}, false
+}
+
+// perform applies delay and connection actions.
+// Returns false if the connection was reset and the response must not be written.
+func (a *Action) perform(w http.ResponseWriter, r *http.Request) bool {
+	if a.Delay > 0 {
+		t := time.NewTimer(a.Delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-r.Context().Done():
+			return false
+		}
+	}
+	if a.Reset {
+		if c, ok := r.Context().Value(connContextKey{}).(net.Conn); ok {
+			if tc, ok := c.(*net.TCPConn); ok {
+				tc.SetLinger(0)
+			}
+			c.Close()
+			return false
+		}
+		// Without the connection only the stream can be reset.
+		panic(http.ErrAbortHandler)
+	}
+	if a.GoAway {
+		// HTTP/2 server sends GOAWAY for "Connection: close" response header.
+		w.Header().Set("Connection", "close")
+	}
+	return true
+}
+
+type connContextKey struct{}
+
+// ConnContext stores the connection in the request context,
+// so Action.Reset can close the connection.
+// Use it as ConnContext of http.Server serving Handler, Server sets it itself.
+// The connection is reset with RST only if c is *net.TCPConn,
+// TLS connection is closed gracefully, Server resets the TCP connection under TLS.
+func ConnContext(ctx context.Context, c net.Conn) context.Context {
+	return context.WithValue(ctx, connContextKey{}, c)
+}
+
+// connListener keeps accepted connections by remote address,
+// so TCP connections under TLS connections can be found by Server.
+type connListener struct {
+	net.Listener
+
+	mu    sync.Mutex
+	conns map[string]net.Conn
+}
+
+func newConnListener(l net.Listener) *connListener {
+	return &connListener{
+		Listener: l,
+		conns:    make(map[string]net.Conn),
+	}
+}
+
+// Accept implements net.Listener.
+func (l *connListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	addr := c.RemoteAddr().String()
+	l.mu.Lock()
+	l.conns[addr] = c
+	l.mu.Unlock()
+	return &trackedConn{Conn: c, l: l, addr: addr}, nil
+}
+
+// conn returns the accepted connection with remote address addr or nil.
+func (l *connListener) conn(addr string) net.Conn {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conns[addr]
+}
+
+// connContext stores the accepted connection of c in the context,
+// c itself if it was not accepted by l.
+func (l *connListener) connContext(ctx context.Context, c net.Conn) context.Context {
+	if raw := l.conn(c.RemoteAddr().String()); raw != nil {
+		c = raw
+	}
+	return ConnContext(ctx, c)
+}
+
+// trackedConn is a connection of connListener forgotten on Close.
+type trackedConn struct {
+	net.Conn
+	l    *connListener
+	addr string
+}
+
+// Close implements net.Conn.
+func (c *trackedConn) Close() error {
+	c.l.mu.Lock()
+	if c.l.conns[c.addr] == c.Conn {
+		delete(c.l.conns, c.addr)
+	}
+	c.l.mu.Unlock()
+	return c.Conn.Close()
+}
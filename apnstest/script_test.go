@@ -0,0 +1,246 @@
+package apnstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+func TestScriptDevice(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	at := time.Date(2021, 8, 15, 4, 0, 0, 0, time.UTC)
+	s.ScriptDevice(deviceToken, TooManyRequests(), Shutdown(), Unregistered(at))
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	}
+
+	want := []struct {
+		status    int
+		reason    string
+		timestamp int64
+	}{
+		{apns.Status429, apns.ReasonTooManyRequests, 0},
+		{apns.Status503, apns.ReasonShutdown, 0},
+		{apns.Status410, apns.ReasonUnregistered, 1629000000000},
+		{apns.Status200, "", 0},
+	}
+	for i, w := range want {
+		res, err := client.Push(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != w.status || res.Reason != w.reason || res.Timestamp != w.timestamp {
+			t.Errorf("%v: got: %v %v %v; want: %v %v %v", i, res.Status, res.Reason, res.Timestamp, w.status, w.reason, w.timestamp)
+		}
+	}
+}
+
+func TestScriptRequest(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	s.ScriptRequest(2, Shutdown())
+	s.ScriptDevice(deviceToken, TooManyRequests())
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	}
+
+	reasons := []string{apns.ReasonTooManyRequests, apns.ReasonShutdown, ""}
+	for i, want := range reasons {
+		res, err := client.Push(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Reason != want {
+			t.Errorf("%v: got: %q; want: %q", i, res.Reason, want)
+		}
+	}
+
+	// Checks take precedence over scripted status.
+	s.ScriptDevice(deviceToken, Shutdown())
+	n.Topic = ""
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != apns.ReasonMissingTopic {
+		t.Errorf("got: %v; want: %v", res.Reason, apns.ReasonMissingTopic)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	s.Unregister(deviceToken, time.Now())
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	}
+	for i := 0; i < 2; i++ {
+		res, err := client.Push(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != apns.Status410 || res.Timestamp == 0 {
+			t.Errorf("got: %v %v; want: 410 with timestamp", res.Status, res.Timestamp)
+		}
+	}
+
+	s.Reset()
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != apns.Status200 {
+		t.Errorf("got: %v; want: 200 after reset", res.Status)
+	}
+}
+
+func TestScriptSlow(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	s.ScriptDevice(deviceToken, Slow(time.Second))
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := client.PushWithContext(ctx, n)
+	if err == nil {
+		t.Error("err must be not nil")
+	}
+}
+
+func TestScriptConnection(t *testing.T) {
+	s := NewUnstartedServer()
+	var conns int32
+	s.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	s.StartTLS()
+	defer s.Close()
+	client := newTestClient(t, s)
+
+	s.ScriptRequest(2, GoAway())
+	s.ScriptRequest(4, ConnectionReset())
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	}
+	for i := 1; i <= 3; i++ {
+		res, err := client.Push(n)
+		if err != nil {
+			t.Fatalf("%v: %v", i, err)
+		}
+		if res.Status != apns.Status200 {
+			t.Errorf("%v: got: %v; want: 200", i, res.Status)
+		}
+	}
+	if c := atomic.LoadInt32(&conns); c != 2 {
+		t.Errorf("connections: %v; want: 2 after GOAWAY", c)
+	}
+
+	_, err := client.Push(n)
+	if !errors.Is(err, syscall.ECONNRESET) && !strings.Contains(fmt.Sprint(err), "connection reset") {
+		t.Errorf("err: %v; want: connection reset", err)
+	}
+	if len(s.Pushes()) != 4 {
+		t.Errorf("len(pushes): %v; want: 4", len(s.Pushes()))
+	}
+}
+
+func TestScriptClientRetry(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+	client.RetryPolicy = &apns.Backoff{MinDelay: time.Millisecond}
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	}
+	// The connection is reset after the client read all data sent before.
+	if _, err := client.Push(n); err != nil {
+		t.Fatal(err)
+	}
+	s.Reset()
+
+	s.ScriptRequest(1, ConnectionReset())
+	s.ScriptRequest(2, Shutdown())
+	s.ScriptRequest(3, GoAway())
+
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != apns.Status200 {
+		t.Errorf("got: %v %v; want: 200", res.Status, res.Reason)
+	}
+
+	pushes := s.Pushes()
+	if len(pushes) != 3 {
+		t.Fatalf("len(pushes): %v; want: 3", len(pushes))
+	}
+	for _, p := range pushes {
+		if p.Notification.ID != pushes[0].Notification.ID {
+			t.Errorf("apns-id: %v; want: %v", p.Notification.ID, pushes[0].Notification.ID)
+		}
+	}
+}
+
+func TestScriptClientRetryPossiblyDelivered(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+	client.RetryPolicy = &apns.Backoff{MinDelay: time.Millisecond}
+
+	// Reset of a new connection is seen as unexpected EOF, see Action.Reset.
+	s.ScriptRequest(1, ConnectionReset())
+
+	_, err := client.Push(&apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("err: %v; want: unexpected EOF", err)
+	}
+	if len(s.Pushes()) != 1 {
+		t.Errorf("len(pushes): %v; want: 1, possibly delivered request must not be retried", len(s.Pushes()))
+	}
+}
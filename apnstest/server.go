@@ -0,0 +1,430 @@
+// Package apnstest provides a fake APNs server for testing remote notification providers.
+package apnstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+var (
+	uuidRegexp        = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	deviceTokenRegexp = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+)
+
+// Headers of notification request that must not be repeated.
+var singleHeaders = []string{
+	"authorization",
+	"apns-id",
+	"apns-topic",
+	"apns-push-type",
+	"apns-expiration",
+	"apns-priority",
+	"apns-collapse-id",
+}
+
+// Push is a notification request received by Handler.
+type Push struct {
+	// Notification restored from the request path, headers and body.
+	// Payload is raw JSON bytes of the request body.
+	Notification apns.Notification
+
+	// Headers of the request.
+	Header http.Header
+
+	// Key ID and Team ID of the provider token the request was signed with.
+	// Empty for certificate-based connection.
+	KeyID  string
+	TeamID string
+
+	// Response sent back to the provider.
+	Response apns.Response
+
+	// Time at the request was received.
+	Time time.Time
+}
+
+type key struct {
+	teamID    string
+	publicKey *ecdsa.PublicKey
+}
+
+// Handler is an http.Handler that implements the /3/device/<device_token> endpoint of APNs.
+// It verifies provider tokens and client certificates,
+// checks request headers and payload like APNs does
+// and answers with the corresponding status and reason.
+//
+// Failures can be scripted per device token or per request count,
+// see ScriptDevice, ScriptRequest and Unregister.
+type Handler struct {
+	// Clock for Push.Time and expiration of provider tokens,
+	// nil means the system time. See Clock.
+	Clock apns.Clock
+
+	// OnPush, if not nil, is called with every received notification request
+	// before the response is sent, for example, to log requests.
+	// It may be called concurrently.
+	OnPush func(p *Push)
+
+	// Maximum number of kept notification requests returned by Pushes,
+	// the oldest ones are forgotten when it is exceeded. 0 means no limit.
+	MaxPushes int
+
+	mu     sync.Mutex
+	keys   map[string]key
+	certs  []*x509.Certificate
+	pushes []*Push
+	count  int
+
+	requests     map[int]Action
+	devices      map[string][]Action
+	unregistered map[string]time.Time
+}
+
+// NewHandler returns Handler without registered keys and certificates.
+func NewHandler() *Handler {
+	return &Handler{
+		keys:         make(map[string]key),
+		requests:     make(map[int]Action),
+		devices:      make(map[string][]Action),
+		unregistered: make(map[string]time.Time),
+	}
+}
+
+// AddKey registers public key of an authentication token signing key
+// with keyID and teamID for verifying provider tokens.
+func (h *Handler) AddKey(keyID, teamID string, publicKey *ecdsa.PublicKey) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.keys[keyID] = key{teamID: teamID, publicKey: publicKey}
+}
+
+// RemoveKey unregisters key with keyID, provider tokens signed with it become invalid.
+func (h *Handler) RemoveKey(keyID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.keys, keyID)
+}
+
+// AddCertificate registers provider certificate for certificate-based connection.
+// The handler allows topics returned by apns.CertificateTopics for cert.
+func (h *Handler) AddCertificate(cert *x509.Certificate) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.certs = append(h.certs, cert)
+}
+
+// Pushes returns all received notification requests in order of arrival.
+func (h *Handler) Pushes() []*Push {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	pushes := make([]*Push, len(h.pushes))
+	copy(pushes, h.pushes)
+	return pushes
+}
+
+// Reset forgets received notification requests, resets request count
+// and drops scripted actions and unregistered device tokens.
+func (h *Handler) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.pushes = nil
+	h.count = 0
+	h.requests = make(map[int]Action)
+	h.devices = make(map[string][]Action)
+	h.unregistered = make(map[string]time.Time)
+}
+
+func (h *Handler) now() time.Time {
+	if h.Clock == nil {
+		return time.Now()
+	}
+	return h.Clock.Now()
+}
+
+// ServeHTTP handles notification request.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return
+	}
+
+	h.mu.Lock()
+	h.count++
+	count := h.count
+	h.mu.Unlock()
+
+	p := &Push{
+		Notification: apns.Notification{
+			DeviceToken: strings.TrimPrefix(r.URL.Path, "/3/device/"),
+			ID:          r.Header.Get("apns-id"),
+			Topic:       r.Header.Get("apns-topic"),
+			PushType:    r.Header.Get("apns-push-type"),
+			Expiration:  r.Header.Get("apns-expiration"),
+			CollapseID:  r.Header.Get("apns-collapse-id"),
+			Payload:     body,
+		},
+		Header: r.Header.Clone(),
+		Time:   h.now(),
+	}
+	p.Notification.Priority, _ = strconv.Atoi(r.Header.Get("apns-priority"))
+
+	p.Response.ID = p.Notification.ID
+	if p.Response.ID == "" || !uuidRegexp.MatchString(p.Response.ID) {
+		p.Response.ID = apns.NewID()
+	}
+	p.Response.Status, p.Response.Reason = h.check(r, p, body)
+
+	action, scripted := h.action(count, p.Notification.DeviceToken)
+	if scripted && action.Status != 0 && p.Response.Status == apns.Status200 {
+		p.Response.Status = action.Status
+		p.Response.Reason = action.Reason
+		p.Response.Timestamp = action.Timestamp
+	}
+
+	h.mu.Lock()
+	h.pushes = append(h.pushes, p)
+	if h.MaxPushes > 0 {
+		for len(h.pushes) > h.MaxPushes {
+			// Released for GC, the array is reallocated by append later.
+			h.pushes[0] = nil
+			h.pushes = h.pushes[1:]
+		}
+	}
+	h.mu.Unlock()
+
+	if h.OnPush != nil {
+		h.OnPush(p)
+	}
+
+	if scripted && !action.perform(w, r) {
+		return
+	}
+	writeResponse(w, &p.Response)
+}
+
+// check validates request like APNs does.
+// Returns apns.Status200 or failure status and reason.
+func (h *Handler) check(r *http.Request, p *Push, body []byte) (int, string) {
+	if r.Method != http.MethodPost {
+		return apns.Status405, apns.ReasonMethodNotAllowed
+	}
+	if !strings.HasPrefix(r.URL.Path, "/3/device/") {
+		return apns.Status404, apns.ReasonBadPath
+	}
+
+	for _, name := range singleHeaders {
+		if len(r.Header.Values(name)) > 1 {
+			return apns.Status400, apns.ReasonDuplicateHeaders
+		}
+	}
+
+	n := &p.Notification
+	if n.DeviceToken == "" {
+		return apns.Status400, apns.ReasonMissingDeviceToken
+	}
+
+	topics, status, reason := h.authorize(r, p)
+	if status != apns.Status200 {
+		return status, reason
+	}
+
+	if n.ID != "" && !uuidRegexp.MatchString(n.ID) {
+		return apns.Status400, apns.ReasonBadMessageId
+	}
+	if !deviceTokenRegexp.MatchString(n.DeviceToken) {
+		return apns.Status400, apns.ReasonBadDeviceToken
+	}
+
+	if n.Topic == "" {
+		if len(topics) != 1 {
+			return apns.Status400, apns.ReasonMissingTopic
+		}
+		n.Topic = topics[0]
+	}
+	if topics != nil && !contains(topics, n.Topic) {
+		return apns.Status400, apns.ReasonTopicDisallowed
+	}
+
+	switch n.PushType {
+	case "", apns.PushTypeAlert, apns.PushTypeBackground, apns.PushTypeMDM:
+	case apns.PushTypeVoIP:
+		if !strings.HasSuffix(n.Topic, ".voip") {
+			return apns.Status400, apns.ReasonBadTopic
+		}
+	case apns.PushTypeComplication:
+		if !strings.HasSuffix(n.Topic, ".complication") {
+			return apns.Status400, apns.ReasonBadTopic
+		}
+	case apns.PushTypeFileprovider:
+		if !strings.HasSuffix(n.Topic, ".pushkit.fileprovider") {
+			return apns.Status400, apns.ReasonBadTopic
+		}
+	case apns.PushTypeLiveActivity:
+		if !strings.HasSuffix(n.Topic, ".push-type.liveactivity") {
+			return apns.Status400, apns.ReasonBadTopic
+		}
+	default:
+		return apns.Status400, apns.ReasonInvalidPushType
+	}
+
+	if v := r.Header.Get("apns-priority"); v != "" {
+		switch v {
+		case "1", "5":
+		case "10":
+			if n.PushType == apns.PushTypeBackground {
+				return apns.Status400, apns.ReasonBadPriority
+			}
+		default:
+			return apns.Status400, apns.ReasonBadPriority
+		}
+	}
+
+	if n.Expiration != "" {
+		if e, err := strconv.ParseInt(n.Expiration, 10, 64); err != nil || e < 0 {
+			return apns.Status400, apns.ReasonBadExpirationDate
+		}
+	}
+
+	if len(n.CollapseID) > apns.MaxCollapseIDSize {
+		return apns.Status400, apns.ReasonBadCollapseId
+	}
+
+	if len(body) == 0 {
+		return apns.Status400, apns.ReasonPayloadEmpty
+	}
+	max := apns.MaxPayloadSize
+	if n.PushType == apns.PushTypeVoIP {
+		max = apns.MaxVoIPPayloadSize
+	}
+	if len(body) > max {
+		return apns.Status413, apns.ReasonPayloadTooLarge
+	}
+
+	return apns.Status200, ""
+}
+
+// authorize verifies provider token or client certificate of request.
+// Returns topics allowed by client certificate or nil for provider token.
+func (h *Handler) authorize(r *http.Request, p *Push) ([]string, int, string) {
+	auth := r.Header.Get("authorization")
+	if auth == "" {
+		if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+			cert := r.TLS.PeerCertificates[0]
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			for _, c := range h.certs {
+				if c.Equal(cert) {
+					return apns.CertificateTopics(cert), apns.Status200, ""
+				}
+			}
+			return nil, apns.Status403, apns.ReasonBadCertificate
+		}
+		return nil, apns.Status403, apns.ReasonMissingProviderToken
+	}
+
+	if !strings.HasPrefix(strings.ToLower(auth), "bearer ") {
+		return nil, apns.Status403, apns.ReasonInvalidProviderToken
+	}
+	b, err := apns.ParseBearer(auth)
+	if err != nil {
+		return nil, apns.Status403, apns.ReasonInvalidProviderToken
+	}
+	p.KeyID = b.KeyID
+	p.TeamID = b.TeamID
+
+	h.mu.Lock()
+	k, ok := h.keys[b.KeyID]
+	h.mu.Unlock()
+	if !ok || k.teamID != b.TeamID || b.Verify(k.publicKey) != nil {
+		return nil, apns.Status403, apns.ReasonInvalidProviderToken
+	}
+	if b.Expired(p.Time) {
+		return nil, apns.Status403, apns.ReasonExpiredProviderToken
+	}
+	return nil, apns.Status200, ""
+}
+
+// Server is a TLS HTTP/2 server with Handler.
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// NewServer starts and returns a new Server.
+// The caller should call Close when finished, to shut it down.
+func NewServer() *Server {
+	s := NewUnstartedServer()
+	s.StartTLS()
+	return s
+}
+
+// NewUnstartedServer returns a new Server but doesn't start it.
+// After changing its configuration, the caller should call StartTLS.
+func NewUnstartedServer() *Server {
+	h := NewHandler()
+	ts := httptest.NewUnstartedServer(h)
+	ts.EnableHTTP2 = true
+	ts.TLS = &tls.Config{
+		ClientAuth: tls.RequestClientCert,
+	}
+	return &Server{
+		Server:  ts,
+		Handler: h,
+	}
+}
+
+// StartTLS starts TLS on the server.
+// The listener can be replaced before, for example, to listen on a specific address.
+func (s *Server) StartTLS() {
+	// TLS connections are closed gracefully, Action.Reset needs TCP connections under them.
+	l := newConnListener(s.Listener)
+	s.Listener = l
+	s.Config.ConnContext = l.connContext
+	s.Server.StartTLS()
+}
+
+// CertificateClient returns HTTP/2 client that presents provider certificate cert
+// and trusts the server TLS certificate.
+func (s *Server) CertificateClient(cert tls.Certificate) *http.Client {
+	c := apns.CertificateHTTPClient(cert)
+	roots := x509.NewCertPool()
+	roots.AddCert(s.Certificate())
+	c.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+	return c
+}
+
+func writeResponse(w http.ResponseWriter, res *apns.Response) {
+	w.Header().Set("apns-id", res.ID)
+	if res.Status == apns.Status200 {
+		w.WriteHeader(res.Status)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(res.Status)
+	json.NewEncoder(w).Encode(struct {
+		Reason    string `json:"reason"`
+		Timestamp int64  `json:"timestamp,omitempty"`
+	}{res.Reason, res.Timestamp})
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,339 @@
+package apnstest
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+const deviceToken = "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e"
+
+func newTestServer(t *testing.T) (*Server, *apns.Client) {
+	s := NewServer()
+	return s, newTestClient(t, s)
+}
+
+func newTestClient(t *testing.T, s *Server) *apns.Client {
+	key, err := apns.AuthKeyFromFile("../testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.AddKey("5MDQ4KLTY7", "SUPERTEEM1", &key.PublicKey)
+	return apns.NewClient(apns.NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"), s.Client())
+}
+
+func TestServerPush(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		ID:          "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D",
+		Topic:       "com.example.app",
+		PushType:    apns.PushTypeAlert,
+		Priority:    apns.PriorityHigh,
+		Expiration:  "0",
+		CollapseID:  "hello",
+		Payload:     `{"aps":{"alert":"Hello"}}`,
+	}
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != apns.Status200 {
+		t.Fatalf("res.Status: %v; want: %v (%v)", res.Status, apns.Status200, res.Reason)
+	}
+	if res.ID != n.ID {
+		t.Errorf("res.ID: %v; want: %v", res.ID, n.ID)
+	}
+
+	pushes := s.Pushes()
+	if len(pushes) != 1 {
+		t.Fatalf("len(pushes): %v; want: 1", len(pushes))
+	}
+	p := pushes[0]
+	if p.KeyID != "5MDQ4KLTY7" || p.TeamID != "SUPERTEEM1" {
+		t.Errorf("key: %v %v; want: 5MDQ4KLTY7 SUPERTEEM1", p.KeyID, p.TeamID)
+	}
+	if p.Notification.DeviceToken != deviceToken {
+		t.Errorf("DeviceToken: %v; want: %v", p.Notification.DeviceToken, deviceToken)
+	}
+	if p.Notification.Priority != apns.PriorityHigh {
+		t.Errorf("Priority: %v; want: %v", p.Notification.Priority, apns.PriorityHigh)
+	}
+	if string(p.Notification.Payload.([]byte)) != `{"aps":{"alert":"Hello"}}` {
+		t.Errorf("Payload: %s", p.Notification.Payload)
+	}
+
+	s.Reset()
+	if len(s.Pushes()) != 0 {
+		t.Error("pushes must be empty after reset")
+	}
+}
+
+func TestServerGeneratesID(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	res, err := client.Push(&apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !uuidRegexp.MatchString(res.ID) {
+		t.Errorf("res.ID: %v; want UUID", res.ID)
+	}
+}
+
+func TestServerOnPush(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	var pushed *Push
+	s.OnPush = func(p *Push) {
+		pushed = p
+	}
+	res, err := client.Push(&apns.Notification{
+		DeviceToken: "xxx",
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pushed == nil {
+		t.Fatal("OnPush must be called")
+	}
+	if pushed.Response.Reason != apns.ReasonBadDeviceToken || pushed.Response.ID != res.ID {
+		t.Errorf("pushed.Response: %+v; want: %+v", pushed.Response, res)
+	}
+}
+
+func TestServerMaxPushes(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+	s.MaxPushes = 2
+
+	var ids []string
+	for i := 0; i < 5; i++ {
+		res, err := client.Push(&apns.Notification{
+			DeviceToken: deviceToken,
+			Host:        s.URL,
+			Topic:       "com.example.app",
+			Payload:     `{}`,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, res.ID)
+	}
+
+	pushes := s.Pushes()
+	if len(pushes) != 2 {
+		t.Fatalf("len(pushes): %v; want: 2", len(pushes))
+	}
+	if pushes[0].Response.ID != ids[3] || pushes[1].Response.ID != ids[4] {
+		t.Error("the latest pushes must be kept")
+	}
+}
+
+func TestServerRejects(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	valid := func() *apns.Notification {
+		return &apns.Notification{
+			DeviceToken: deviceToken,
+			Host:        s.URL,
+			Topic:       "com.example.app",
+			Payload:     `{"aps":{"alert":"Hello"}}`,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(n *apns.Notification)
+		status int
+		reason string
+	}{
+		{"missing device token", func(n *apns.Notification) { n.DeviceToken = "" }, apns.Status400, apns.ReasonMissingDeviceToken},
+		{"bad device token", func(n *apns.Notification) { n.DeviceToken = "xyz" }, apns.Status400, apns.ReasonBadDeviceToken},
+		{"bad id", func(n *apns.Notification) { n.ID = "123" }, apns.Status400, apns.ReasonBadMessageId},
+		{"missing topic", func(n *apns.Notification) { n.Topic = "" }, apns.Status400, apns.ReasonMissingTopic},
+		{"invalid push type", func(n *apns.Notification) { n.PushType = "unknown" }, apns.Status400, apns.ReasonInvalidPushType},
+		{"voip topic", func(n *apns.Notification) { n.PushType = apns.PushTypeVoIP }, apns.Status400, apns.ReasonBadTopic},
+		{"bad priority", func(n *apns.Notification) { n.Priority = 7 }, apns.Status400, apns.ReasonBadPriority},
+		{"background priority", func(n *apns.Notification) {
+			n.PushType = apns.PushTypeBackground
+			n.Priority = apns.PriorityHigh
+		}, apns.Status400, apns.ReasonBadPriority},
+		{"bad expiration", func(n *apns.Notification) { n.Expiration = "tomorrow" }, apns.Status400, apns.ReasonBadExpirationDate},
+		{"bad collapse id", func(n *apns.Notification) { n.CollapseID = strings.Repeat("x", 65) }, apns.Status400, apns.ReasonBadCollapseId},
+		{"payload empty", func(n *apns.Notification) { n.Payload = nil }, apns.Status400, apns.ReasonPayloadEmpty},
+		{"payload too large", func(n *apns.Notification) {
+			n.Payload = `{"a":"` + strings.Repeat("x", apns.MaxPayloadSize) + `"}`
+		}, apns.Status413, apns.ReasonPayloadTooLarge},
+	}
+
+	for _, tt := range tests {
+		n := valid()
+		tt.modify(n)
+		res, err := client.Push(n)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+		if res.Status != tt.status || res.Reason != tt.reason {
+			t.Errorf("%v: got: %v %v; want: %v %v", tt.name, res.Status, res.Reason, tt.status, tt.reason)
+		}
+	}
+
+	n := valid()
+	n.PushType = apns.PushTypeVoIP
+	n.Topic = "com.example.app.voip"
+	n.Payload = `{"a":"` + strings.Repeat("x", apns.MaxPayloadSize) + `"}`
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != apns.Status200 {
+		t.Errorf("voip payload: got: %v %v; want: 200", res.Status, res.Reason)
+	}
+}
+
+func TestServerAuthorization(t *testing.T) {
+	s, client := newTestServer(t)
+	defer s.Close()
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Topic:       "com.example.app",
+		Payload:     `{}`,
+	}
+
+	push := func(set func(h http.Header)) *apns.Response {
+		req, err := n.BuildRequest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		set(req.Header)
+		r, err := s.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := apns.ParseResponse(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	res := push(func(h http.Header) {})
+	if res.Reason != apns.ReasonMissingProviderToken {
+		t.Errorf("got: %v; want: %v", res.Reason, apns.ReasonMissingProviderToken)
+	}
+
+	res = push(func(h http.Header) { apns.SetBearer(h, "xxx") })
+	if res.Reason != apns.ReasonInvalidProviderToken {
+		t.Errorf("got: %v; want: %v", res.Reason, apns.ReasonInvalidProviderToken)
+	}
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bearer, err := apns.GenerateBearer(other, "5MDQ4KLTY7", "SUPERTEEM1", time.Now().Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = push(func(h http.Header) { apns.SetBearer(h, bearer) })
+	if res.Reason != apns.ReasonInvalidProviderToken {
+		t.Errorf("got: %v; want: %v", res.Reason, apns.ReasonInvalidProviderToken)
+	}
+
+	client.Token.IssuedAt = time.Now().Add(-2 * time.Hour).Unix()
+	client.Token.Bearer, err = apns.GenerateBearer(client.Token.Key, "5MDQ4KLTY7", "SUPERTEEM1", client.Token.IssuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res = push(func(h http.Header) { apns.SetBearer(h, client.Token.Bearer) })
+	if res.Status != apns.Status403 || res.Reason != apns.ReasonExpiredProviderToken {
+		t.Errorf("got: %v %v; want: 403 %v", res.Status, res.Reason, apns.ReasonExpiredProviderToken)
+	}
+
+	s.RemoveKey("5MDQ4KLTY7")
+	res, err = client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != apns.ReasonInvalidProviderToken {
+		t.Errorf("got: %v; want: %v", res.Reason, apns.ReasonInvalidProviderToken)
+	}
+}
+
+func TestServerCertificate(t *testing.T) {
+	cert, err := apns.CertificateFromP12File("../testdata/Certificate.p12", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer()
+	defer s.Close()
+
+	client := apns.NewCertificateClient(cert)
+	client.HTTPClient = s.CertificateClient(cert)
+
+	n := &apns.Notification{
+		DeviceToken: deviceToken,
+		Host:        s.URL,
+		Payload:     `{}`,
+	}
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != apns.ReasonBadCertificate {
+		t.Errorf("got: %v; want: %v", res.Reason, apns.ReasonBadCertificate)
+	}
+
+	s.AddCertificate(cert.Leaf)
+	res, err = client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != apns.ReasonMissingTopic {
+		t.Errorf("got: %v; want: %v", res.Reason, apns.ReasonMissingTopic)
+	}
+
+	n.Topic = "com.example.app.voip"
+	n.PushType = apns.PushTypeVoIP
+	res, err = client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != apns.Status200 {
+		t.Errorf("got: %v %v; want: 200", res.Status, res.Reason)
+	}
+
+	n.Topic = "com.example.other"
+	n.PushType = apns.PushTypeAlert
+	res, err = client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != apns.ReasonTopicDisallowed {
+		t.Errorf("got: %v; want: %v", res.Reason, apns.ReasonTopicDisallowed)
+	}
+}
@@ -0,0 +1,97 @@
+package apns
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// BearerCache shares bearers of tokens with the same KeyID and TeamID
+// between processes, for example, pods of one host,
+// to avoid TooManyProviderTokenUpdates when every process generates its own bearer.
+type BearerCache interface {
+	// Update calls update with the bearer and its issuedAt cached for keyID and teamID,
+	// empty bearer and 0 if there is no cached one,
+	// and caches the bearer and issuedAt update returns, unless it returns error.
+	// Calls of Update for the same keyID and teamID must not run concurrently,
+	// including calls from other processes sharing the cache.
+	Update(keyID, teamID string, update func(bearer string, issuedAt int64) (string, int64, error)) error
+}
+
+// FileBearerCache is BearerCache keeping bearers in JSON files of a directory,
+// one file per KeyID and TeamID, locked by a file lock during Update.
+type FileBearerCache struct {
+	dir string
+}
+
+// NewFileBearerCache returns cache keeping bearers in the dir directory.
+// The directory is created on first Update if it does not exist.
+func NewFileBearerCache(dir string) *FileBearerCache {
+	return &FileBearerCache{dir: dir}
+}
+
+// cachedBearer is the content of a cache file.
+type cachedBearer struct {
+	Bearer   string `json:"bearer"`
+	IssuedAt int64  `json:"issued_at"`
+}
+
+// Update implements BearerCache.
+func (c *FileBearerCache) Update(keyID, teamID string, update func(bearer string, issuedAt int64) (string, int64, error)) error {
+	if err := os.MkdirAll(c.dir, 0700); err != nil {
+		return err
+	}
+
+	name := filepath.Join(c.dir, teamID+"."+keyID+".json")
+	unlock, err := lockFile(name + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	var cached cachedBearer
+	b, err := os.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(b) > 0 {
+		// Broken file is regenerated.
+		if err := json.Unmarshal(b, &cached); err != nil {
+			cached = cachedBearer{}
+		}
+	}
+
+	bearer, issuedAt, err := update(cached.Bearer, cached.IssuedAt)
+	if err != nil {
+		return err
+	}
+	if bearer == cached.Bearer && issuedAt == cached.IssuedAt {
+		return nil
+	}
+
+	b, err = json.Marshal(cachedBearer{Bearer: bearer, IssuedAt: issuedAt})
+	if err != nil {
+		return err
+	}
+	return writeFile(name, b)
+}
+
+// writeFile replaces the named file with data
+// so other processes never read a partially written file.
+func writeFile(name string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), name)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
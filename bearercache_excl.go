@@ -0,0 +1,35 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd
+
+package apns
+
+import (
+	"os"
+	"time"
+)
+
+// Lock file older than staleLockAge is left by a crashed process and removed.
+const staleLockAge = 10 * time.Second
+
+// lockFile locks the named file by creating it exclusively,
+// waiting while it exists. The lock is released by the returned unlock.
+func lockFile(name string) (unlock func(), err error) {
+	for {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(name)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) > staleLockAge {
+			os.Remove(name)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,178 @@
+package apns
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileBearerCache(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(t.TempDir(), "bearers")
+
+	a := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	a.Cache = NewFileBearerCache(dir)
+	bearer, err := a.GenerateIfExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	b.Cache = NewFileBearerCache(dir)
+	cached, err := b.GenerateIfExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != bearer {
+		t.Errorf("cached: %v; want: %v", cached, bearer)
+	}
+	if b.IssuedAt != a.IssuedAt {
+		t.Errorf("b.IssuedAt: %v; want: %v", b.IssuedAt, a.IssuedAt)
+	}
+
+	// Other key is cached separately.
+	c := NewToken(key, "OTHERKEY01", "SUPERTEEM1")
+	c.Cache = NewFileBearerCache(dir)
+	other, err := c.GenerateIfExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == bearer {
+		t.Error("bearer of other key must not be taken from cache")
+	}
+}
+
+func TestFileBearerCacheExpired(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewFileBearerCache(t.TempDir())
+
+	issuedAt := time.Now().Add(-(TokenRefreshInterval + 60) * time.Second).Unix()
+	expired, err := GenerateBearer(key, "5MDQ4KLTY7", "SUPERTEEM1", issuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cache.Update("5MDQ4KLTY7", "SUPERTEEM1", func(string, int64) (string, int64, error) {
+		return expired, issuedAt, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	token.Cache = cache
+	bearer, err := token.GenerateIfExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bearer == expired {
+		t.Error("expired cached bearer must be regenerated")
+	}
+
+	cache.Update("5MDQ4KLTY7", "SUPERTEEM1", func(b string, i int64) (string, int64, error) {
+		if b != bearer || i != token.IssuedAt {
+			t.Errorf("cached: %v, %v; want: %v, %v", b, i, bearer, token.IssuedAt)
+		}
+		return b, i, nil
+	})
+}
+
+func TestFileBearerCacheBroken(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "SUPERTEEM1.5MDQ4KLTY7.json"), []byte("{broken"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	token.Cache = NewFileBearerCache(dir)
+	bearer, err := token.GenerateIfExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bearer == "" {
+		t.Error("bearer must be generated")
+	}
+}
+
+func TestFileBearerCacheConcurrent(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	bearers := make([]string, 16)
+	var wg sync.WaitGroup
+	for i := range bearers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Each token plays a separate process.
+			token := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+			token.Cache = NewFileBearerCache(dir)
+			bearer, err := token.GenerateIfExpired()
+			bearers[i] = bearer
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, b := range bearers[1:] {
+		if b != bearers[0] {
+			t.Fatalf("bearers must be the same: %v", bearers)
+		}
+	}
+}
+
+func TestTokenRefreshCached(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	a := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	a.Cache = NewFileBearerCache(dir)
+	b := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	b.Cache = NewFileBearerCache(dir)
+
+	// Both processes use the same bearer APNs rejects as expired.
+	a.IssuedAt = time.Now().Add(-(TokenMinRefreshInterval + 60) * time.Second).Unix()
+	a.Bearer, err = GenerateBearer(key, a.KeyID, a.TeamID, a.IssuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.IssuedAt, b.Bearer = a.IssuedAt, a.Bearer
+	rejected := a.Bearer
+
+	refreshed, err := a.Refresh(rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed == rejected {
+		t.Error("bearer must be regenerated")
+	}
+
+	// The other process takes the bearer regenerated by the first one.
+	cached, err := b.Refresh(rejected)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cached != refreshed {
+		t.Errorf("cached: %v; want: %v", cached, refreshed)
+	}
+}
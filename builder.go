@@ -0,0 +1,217 @@
+package apns
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// Background notification can't have alert, badge or sound.
+	ErrBuilderBackgroundAlert = errors.New("builder: background notification with alert, badge or sound")
+)
+
+// Builder builds a notification with presets of its push type
+// and checks it by Notification.Validate.
+//
+//	n, err := apns.NewBuilder(apns.PushTypeBackground, "com.example.app").
+//		DeviceToken(deviceToken).
+//		Custom("sync", true).
+//		Build()
+type Builder struct {
+	n      Notification
+	aps    *APS
+	alert  *Alert
+	custom map[string]interface{}
+}
+
+// NewBuilder returns builder of notification with pushType for the app with bundleID.
+// The topic is bundleID with the suffix pushType requires,
+// for example, .voip for PushTypeVoIP. Presets of push types:
+//   - PushTypeAlert: priority 10.
+//   - PushTypeBackground: priority 5 and content-available 1,
+//     alert, badge and sound are not allowed.
+//   - PushTypeVoIP, PushTypeComplication: priority 10.
+//   - PushTypeLiveActivity: priority 10, use LiveActivity for the payload.
+//   - PushTypeFileprovider, PushTypeMDM: priority is omitted.
+func NewBuilder(pushType, bundleID string) *Builder {
+	b := &Builder{
+		n: Notification{
+			Topic:    bundleID + topicSuffixes[pushType],
+			PushType: pushType,
+		},
+	}
+	switch pushType {
+	case PushTypeAlert, PushTypeVoIP, PushTypeComplication, PushTypeLiveActivity:
+		b.n.Priority = PriorityHigh
+	case PushTypeBackground:
+		b.n.Priority = PriorityLow
+		b.APS().ContentAvailable = 1
+	}
+	return b
+}
+
+// DeviceToken sets the device token of the notification.
+func (b *Builder) DeviceToken(deviceToken string) *Builder {
+	b.n.DeviceToken = deviceToken
+	return b
+}
+
+// Channel sets the broadcast channel of the notification, see Notification.SetChannel.
+func (b *Builder) Channel(ch *Channel) *Builder {
+	b.n.SetChannel(ch)
+	return b
+}
+
+// Host sets the host of the notification, for example, HostDevelopment.
+func (b *Builder) Host(host string) *Builder {
+	b.n.Host = host
+	return b
+}
+
+// ID sets apns-id of the notification.
+func (b *Builder) ID(id string) *Builder {
+	b.n.ID = id
+	return b
+}
+
+// Priority replaces the priority of the preset.
+func (b *Builder) Priority(priority int) *Builder {
+	b.n.Priority = priority
+	return b
+}
+
+// Expiration sets the expiration date of the notification, see Notification.SetExpiration.
+func (b *Builder) Expiration(t time.Time) *Builder {
+	b.n.SetExpiration(t)
+	return b
+}
+
+// DeliverOnce makes APNs deliver the notification only once, see Notification.SetDeliverOnce.
+func (b *Builder) DeliverOnce() *Builder {
+	b.n.SetDeliverOnce()
+	return b
+}
+
+// CollapseID sets apns-collapse-id of the notification.
+func (b *Builder) CollapseID(id string) *Builder {
+	b.n.CollapseID = id
+	return b
+}
+
+// Title sets the title of the alert.
+func (b *Builder) Title(title string) *Builder {
+	b.Alert().Title = title
+	return b
+}
+
+// Subtitle sets the subtitle of the alert.
+func (b *Builder) Subtitle(subtitle string) *Builder {
+	b.Alert().Subtitle = subtitle
+	return b
+}
+
+// Body sets the body of the alert.
+func (b *Builder) Body(body string) *Builder {
+	b.Alert().Body = body
+	return b
+}
+
+// APS returns aps dictionary of the payload for setting keys Builder has no methods for.
+func (b *Builder) APS() *APS {
+	if b.aps == nil {
+		b.aps = &APS{}
+	}
+	return b.aps
+}
+
+// Alert returns alert of aps dictionary for setting keys Builder has no methods for,
+// for example, localization keys.
+func (b *Builder) Alert() *Alert {
+	if b.alert == nil {
+		b.alert = &Alert{}
+	}
+	return b.alert
+}
+
+// Badge sets the badge of the app icon, 0 removes the badge.
+func (b *Builder) Badge(badge int) *Builder {
+	b.APS().Badge = badge
+	return b
+}
+
+// Sound sets the sound name or Sound, for example, SoundDefault.
+func (b *Builder) Sound(sound interface{}) *Builder {
+	b.APS().Sound = sound
+	return b
+}
+
+// ThreadID sets the identifier for grouping notifications.
+func (b *Builder) ThreadID(id string) *Builder {
+	b.APS().ThreadID = id
+	return b
+}
+
+// Category sets the notification’s type.
+func (b *Builder) Category(category string) *Builder {
+	b.APS().Category = category
+	return b
+}
+
+// MutableContent lets the notification service app extension modify the notification.
+func (b *Builder) MutableContent() *Builder {
+	b.APS().MutableContent = 1
+	return b
+}
+
+// InterruptionLevel sets the importance and delivery timing of the notification,
+// for example, InterruptionLevelTimeSensitive.
+func (b *Builder) InterruptionLevel(level string) *Builder {
+	b.APS().InterruptionLevel = level
+	return b
+}
+
+// LiveActivity sets event, content state and timestamp of the Live Activity.
+func (b *Builder) LiveActivity(event string, contentState interface{}, timestamp time.Time) *Builder {
+	b.APS().Event = event
+	b.APS().ContentState = contentState
+	b.APS().Timestamp = timestamp.Unix()
+	return b
+}
+
+// Custom sets custom key of the payload outside of aps.
+func (b *Builder) Custom(key string, value interface{}) *Builder {
+	if b.custom == nil {
+		b.custom = make(map[string]interface{})
+	}
+	b.custom[key] = value
+	return b
+}
+
+// Build returns the notification checked by Notification.Validate.
+// Every call returns a new notification.
+func (b *Builder) Build() (*Notification, error) {
+	var aps *APS
+	if b.aps != nil || b.alert != nil {
+		aps = &APS{}
+		if b.aps != nil {
+			*aps = *b.aps
+		}
+		if b.alert != nil {
+			alert := *b.alert
+			aps.Alert = &alert
+		}
+	}
+
+	if b.n.PushType == PushTypeBackground && aps != nil && (aps.Alert != nil || aps.Badge != nil || aps.Sound != nil) {
+		return nil, ErrBuilderBackgroundAlert
+	}
+
+	// Payload without aps dictionary, for example, MDM payload, has only custom keys.
+	n := b.n
+	n.Payload = BuildPayload(aps, b.custom)
+
+	if err := n.Validate(); err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
@@ -0,0 +1,145 @@
+package apns
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBuilderPresets(t *testing.T) {
+	const deviceToken = "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e"
+
+	tests := []struct {
+		pushType string
+		topic    string
+		priority int
+		payload  string
+	}{
+		{PushTypeAlert, "com.example.app", PriorityHigh, `{}`},
+		{PushTypeBackground, "com.example.app", PriorityLow, `{"aps":{"content-available":1}}`},
+		{PushTypeVoIP, "com.example.app.voip", PriorityHigh, `{}`},
+		{PushTypeComplication, "com.example.app.complication", PriorityHigh, `{}`},
+		{PushTypeFileprovider, "com.example.app.pushkit.fileprovider", 0, `{}`},
+		{PushTypeLiveActivity, "com.example.app.push-type.liveactivity", PriorityHigh, `{}`},
+		{PushTypeMDM, "com.example.app", 0, `{}`},
+	}
+	for _, test := range tests {
+		n, err := NewBuilder(test.pushType, "com.example.app").DeviceToken(deviceToken).Build()
+		if err != nil {
+			t.Fatalf("%v: %v", test.pushType, err)
+		}
+		if n.PushType != test.pushType {
+			t.Errorf("%v: n.PushType: %v; want: %v", test.pushType, n.PushType, test.pushType)
+		}
+		if n.Topic != test.topic {
+			t.Errorf("%v: n.Topic: %v; want: %v", test.pushType, n.Topic, test.topic)
+		}
+		if n.Priority != test.priority {
+			t.Errorf("%v: n.Priority: %v; want: %v", test.pushType, n.Priority, test.priority)
+		}
+		b, err := n.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.payload {
+			t.Errorf("%v: payload: %s; want: %v", test.pushType, b, test.payload)
+		}
+	}
+}
+
+func TestBuilderAlert(t *testing.T) {
+	expiration := time.Unix(1629032400, 0)
+	b := NewBuilder(PushTypeAlert, "com.example.app").
+		DeviceToken("7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e").
+		Host(HostDevelopment).
+		ID("EC1BF194-B3B2-424A-89A9-5A918A6E6B5D").
+		Expiration(expiration).
+		CollapseID("news").
+		Title("Hello").
+		Subtitle("World").
+		Body("Body").
+		Badge(1).
+		Sound(SoundDefault).
+		ThreadID("thread").
+		Category("NEWS").
+		MutableContent().
+		InterruptionLevel(InterruptionLevelPassive).
+		Custom("id", 42)
+	b.Alert().LocKey = "KEY"
+
+	n, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Host != HostDevelopment || n.ID != "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D" || n.CollapseID != "news" {
+		t.Errorf("headers: %v, %v, %v", n.Host, n.ID, n.CollapseID)
+	}
+	if !n.ExpirationTime().Equal(expiration) {
+		t.Errorf("ExpirationTime: %v; want: %v", n.ExpirationTime(), expiration)
+	}
+
+	payload, err := n.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"aps":{"alert":{"title":"Hello","subtitle":"World","body":"Body","loc-key":"KEY"},"badge":1,"sound":"default","thread-id":"thread","category":"NEWS","mutable-content":1,"interruption-level":"passive"},"id":42}`
+	if string(payload) != want {
+		t.Errorf("payload: %s; want: %v", payload, want)
+	}
+
+	// Next notification doesn't share payload with the built one.
+	b.Title("Bye")
+	next, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Payload.(map[string]interface{})["aps"].(*APS).Alert.(*Alert).Title != "Hello" {
+		t.Error("built notification must not be changed by builder")
+	}
+	if next.Payload.(map[string]interface{})["aps"].(*APS).Alert.(*Alert).Title != "Bye" {
+		t.Error("next notification must have new title")
+	}
+}
+
+func TestBuilderLiveActivity(t *testing.T) {
+	n, err := NewBuilder(PushTypeLiveActivity, "com.example.app").
+		Channel(&Channel{ID: "dHN0LXNyY2gtY2hubA=="}).
+		LiveActivity(LiveActivityEventUpdate, map[string]int{"score": 1}, time.Unix(1629000000, 0)).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !n.DeliverOnce() {
+		t.Errorf("n.Expiration: %v; want: %v", n.Expiration, ExpirationDeliverOnce)
+	}
+	payload, err := n.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"aps":{"timestamp":1629000000,"event":"update","content-state":{"score":1}}}`
+	if string(payload) != want {
+		t.Errorf("payload: %s; want: %v", payload, want)
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	const deviceToken = "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e"
+
+	tests := []struct {
+		b    *Builder
+		want error
+	}{
+		{NewBuilder(PushTypeBackground, "com.example.app").DeviceToken(deviceToken).Title("Hello"), ErrBuilderBackgroundAlert},
+		{NewBuilder(PushTypeBackground, "com.example.app").DeviceToken(deviceToken).Badge(1), ErrBuilderBackgroundAlert},
+		{NewBuilder(PushTypeBackground, "com.example.app").DeviceToken(deviceToken).Sound(SoundDefault), ErrBuilderBackgroundAlert},
+		{NewBuilder(PushTypeBackground, "com.example.app").DeviceToken(deviceToken).Priority(PriorityHigh), ErrReasonBadPriority},
+		{NewBuilder(PushTypeAlert, "com.example.app"), ErrReasonMissingDeviceToken},
+		{NewBuilder(PushTypeAlert, "com.example.app").DeviceToken(deviceToken).ID("1"), ErrReasonBadMessageId},
+	}
+	for _, test := range tests {
+		_, err := test.b.Build()
+		if !errors.Is(err, test.want) {
+			t.Errorf("err: %v; want: %v", err, test.want)
+		}
+	}
+}
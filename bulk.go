@@ -0,0 +1,67 @@
+package apns
+
+import (
+	"context"
+	"sync"
+)
+
+// Default number of workers used by Client.PushAll.
+const DefaultWorkers = 16
+
+// Result of sending a notification by Client.PushAll.
+type Result struct {
+	Notification *Notification
+	Response     *Response
+	Err          error
+}
+
+// PushAll sends notifications received from ns concurrently
+// by workers goroutines sharing the client.
+// Pass workers 0 or less to use DefaultWorkers.
+//
+// Results are streamed back in order of completion.
+// The returned channel is closed when ns is closed and all notifications are sent,
+// or when ctx is done and in-flight requests return.
+// The caller must receive all results until the channel is closed.
+func (c *Client) PushAll(ctx context.Context, ns <-chan *Notification, workers int) <-chan Result {
+	return pushAll(ctx, ns, workers, c.PushWithContext)
+}
+
+// pushAll sends notifications received from ns by push in workers goroutines.
+func pushAll(ctx context.Context, ns <-chan *Notification, workers int, push func(context.Context, *Notification) (*Response, error)) <-chan Result {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	results := make(chan Result, workers)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case n, ok := <-ns:
+					if !ok {
+						return
+					}
+					res, err := push(ctx, n)
+					results <- Result{
+						Notification: n,
+						Response:     res,
+						Err:          err,
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
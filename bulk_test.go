@@ -0,0 +1,104 @@
+package apns
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientPushAll(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var active, maxActive int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			m := atomic.LoadInt32(&maxActive)
+			if a <= m || atomic.CompareAndSwapInt32(&maxActive, m, a) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("apns-id", r.Header.Get("apns-id"))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewClient(NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"), ts.Client())
+
+	const count = 50
+	ns := make(chan *Notification)
+	go func() {
+		defer close(ns)
+		for i := 0; i < count; i++ {
+			ns <- &Notification{
+				Host:    ts.URL,
+				ID:      fmt.Sprintf("EC1BF194-B3B2-424A-89A9-%012d", i),
+				Payload: `{}`,
+			}
+		}
+	}()
+
+	ids := make(map[string]bool)
+	for r := range client.PushAll(context.Background(), ns, 4) {
+		if r.Err != nil {
+			t.Fatal(r.Err)
+		}
+		if r.Response.ID != r.Notification.ID {
+			t.Errorf("res.ID: %v; want: %v", r.Response.ID, r.Notification.ID)
+		}
+		ids[r.Response.ID] = true
+	}
+	if len(ids) != count {
+		t.Errorf("results: %v; want: %v", len(ids), count)
+	}
+	if m := atomic.LoadInt32(&maxActive); m > 4 || m < 2 {
+		t.Errorf("concurrent requests: %v; want from 2 to 4", m)
+	}
+}
+
+func TestClientPushAllCancel(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewClient(NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"), ts.Client())
+
+	ns := make(chan *Notification) // never closed
+	go func() {
+		for i := 0; i < 2; i++ {
+			ns <- &Notification{Host: ts.URL, Payload: `{}`}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	n := 0
+	for r := range client.PushAll(ctx, ns, 0) {
+		if r.Err == nil {
+			t.Error("err must be not nil")
+		}
+		n++
+	}
+	if n != 2 {
+		t.Errorf("results: %v; want: 2", n)
+	}
+}
@@ -0,0 +1,202 @@
+package apns
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"os"
+
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// Provider Certificate
+// See https://developer.apple.com/documentation/usernotifications/setting_up_a_remote_notification_server/establishing_a_certificate-based_connection_to_apns.
+
+var (
+	// .p12 file is not PKCS #12 encoded or invalid PKCS #12 file.
+	ErrCertificateBadP12 = errors.New("certificate: invalid PKCS#12")
+
+	// Password of .p12 file is incorrect.
+	ErrCertificateBadPassword = errors.New("certificate: incorrect password")
+
+	// .pem file is not PEM encoded or does not contain certificate and private key.
+	ErrCertificateBadPEM = errors.New("certificate: invalid PEM")
+)
+
+// Object identifiers of Apple extensions in a provider certificate.
+var (
+	// The certificate can connect to the development server.
+	oidDevelopment = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 3, 1}
+
+	// The certificate can connect to the production server.
+	oidProduction = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 3, 2}
+
+	// The topics the certificate supports.
+	oidTopics = asn1.ObjectIdentifier{1, 2, 840, 113635, 100, 6, 3, 6}
+
+	// The UID attribute in the subject of the certificate.
+	oidUID = asn1.ObjectIdentifier{0, 9, 2342, 19200300, 100, 1, 1}
+)
+
+// CertificateFromP12File loads a provider certificate and its private key
+// from the named PKCS #12 file with a .p12 file extension
+// exported from Keychain Access or OpenSSL.
+// Both legacy 3DES and PBES2 (AES, the default of OpenSSL 3) encryption are supported.
+func CertificateFromP12File(name, password string) (tls.Certificate, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return CertificateFromP12Bytes(data, password)
+}
+
+// CertificateFromP12Bytes loads a provider certificate and its private key
+// from the in memory PKCS #12 bytes.
+func CertificateFromP12Bytes(bytes []byte, password string) (tls.Certificate, error) {
+	blocks, err := pkcs12.ToPEM(bytes, password)
+	if err != nil {
+		if err == pkcs12.ErrIncorrectPassword {
+			return tls.Certificate{}, ErrCertificateBadPassword
+		}
+		return tls.Certificate{}, ErrCertificateBadP12
+	}
+
+	var data []byte
+	for _, b := range blocks {
+		data = append(data, pem.EncodeToMemory(b)...)
+	}
+
+	cert, err := certificateFromPEM(data)
+	if err != nil {
+		return tls.Certificate{}, ErrCertificateBadP12
+	}
+	return cert, nil
+}
+
+// CertificateFromPEMFile loads a provider certificate and its unencrypted private key
+// from the named PEM file, that contains both certificate and private key blocks.
+func CertificateFromPEMFile(name string) (tls.Certificate, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return CertificateFromPEMBytes(data)
+}
+
+// CertificateFromPEMBytes loads a provider certificate and its unencrypted private key
+// from the in memory PEM bytes.
+func CertificateFromPEMBytes(bytes []byte) (tls.Certificate, error) {
+	cert, err := certificateFromPEM(bytes)
+	if err != nil {
+		return tls.Certificate{}, ErrCertificateBadPEM
+	}
+	return cert, nil
+}
+
+// certificateFromPEM parses certificate and private key blocks
+// and fills the Leaf field of returned certificate.
+func certificateFromPEM(data []byte) (tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(data, data)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return cert, nil
+}
+
+// CertificateTopics returns the topics the provider certificate supports.
+// The first topic is the bundle ID from the UID attribute of the certificate subject,
+// others are taken from the 1.2.840.113635.100.6.3.6 extension,
+// for example, bundle ID with .voip or .complication suffix.
+func CertificateTopics(cert *x509.Certificate) []string {
+	var topics []string
+	add := func(topic string) {
+		for _, t := range topics {
+			if t == topic {
+				return
+			}
+		}
+		topics = append(topics, topic)
+	}
+
+	for _, name := range cert.Subject.Names {
+		if name.Type.Equal(oidUID) {
+			if uid, ok := name.Value.(string); ok && uid != "" {
+				add(uid)
+			}
+		}
+	}
+
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidTopics) {
+			continue
+		}
+		// The extension is a sequence of topics,
+		// each of them followed by a sequence of its push types.
+		var seq asn1.RawValue
+		if _, err := asn1.Unmarshal(ext.Value, &seq); err != nil {
+			continue
+		}
+		rest := seq.Bytes
+		for len(rest) > 0 {
+			var v asn1.RawValue
+			var err error
+			rest, err = asn1.Unmarshal(rest, &v)
+			if err != nil {
+				break
+			}
+			if v.Class == asn1.ClassUniversal && v.Tag == asn1.TagUTF8String && len(v.Bytes) > 0 {
+				add(string(v.Bytes))
+			}
+		}
+	}
+
+	return topics
+}
+
+// CertificateDevelopment reports whether the provider certificate
+// can connect to the development server.
+func CertificateDevelopment(cert *x509.Certificate) bool {
+	return hasExtension(cert, oidDevelopment)
+}
+
+// CertificateProduction reports whether the provider certificate
+// can connect to the production server.
+func CertificateProduction(cert *x509.Certificate) bool {
+	return hasExtension(cert, oidProduction)
+}
+
+func hasExtension(cert *x509.Certificate, oid asn1.ObjectIdentifier) bool {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oid) {
+			return true
+		}
+	}
+	return false
+}
+
+// CertificateTLSConfig returns TLS configuration for certificate-based connection to APNs.
+func CertificateTLSConfig(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+}
+
+// CertificateHTTPClient returns HTTP/2 client that presents
+// the provider certificate when connecting to APNs.
+func CertificateHTTPClient(cert tls.Certificate) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   CertificateTLSConfig(cert),
+			ForceAttemptHTTP2: true,
+		},
+	}
+}
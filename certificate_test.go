@@ -0,0 +1,113 @@
+package apns
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestCertificateFromP12File(t *testing.T) {
+	// Certificate.p12 is encrypted by legacy 3DES,
+	// CertificateAES.p12 by AES-256-CBC with SHA-256 MAC, the defaults of OpenSSL 3.
+	for _, name := range []string{"testdata/Certificate.p12", "testdata/CertificateAES.p12"} {
+		cert, err := CertificateFromP12File(name, "secret")
+		if err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if cert.Leaf == nil {
+			t.Fatalf("%v: cert.Leaf is nil", name)
+		}
+		if cert.PrivateKey == nil {
+			t.Errorf("%v: cert.PrivateKey is nil", name)
+		}
+		if cert.Leaf.Subject.CommonName != "Apple Push Services: com.example.app" {
+			t.Errorf("%v: got: %v; want: %v", name, cert.Leaf.Subject.CommonName, "Apple Push Services: com.example.app")
+		}
+	}
+}
+
+func TestCertificateFromP12Errors(t *testing.T) {
+	_, err := CertificateFromP12File("testdata/Certificate.p12", "wrong")
+	if err != ErrCertificateBadPassword {
+		t.Errorf("got: %v; want: ErrCertificateBadPassword", err)
+	}
+
+	_, err = CertificateFromP12File("testdata/CertificateAES.p12", "wrong")
+	if err != ErrCertificateBadPassword {
+		t.Errorf("got: %v; want: ErrCertificateBadPassword", err)
+	}
+
+	_, err = CertificateFromP12File("testdata/AuthKey_5MDQ4KLTY7.p8", "secret")
+	if err != ErrCertificateBadP12 {
+		t.Errorf("got: %v; want: ErrCertificateBadP12", err)
+	}
+
+	_, err = CertificateFromP12File("", "secret")
+	if err == nil {
+		t.Error("err must be not nil")
+	}
+}
+
+func TestCertificateFromPEMFile(t *testing.T) {
+	cert, err := CertificateFromPEMFile("testdata/Certificate.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert.Leaf == nil {
+		t.Fatal("cert.Leaf is nil")
+	}
+
+	p12, err := CertificateFromP12File("testdata/Certificate.p12", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cert.Leaf.Equal(p12.Leaf) {
+		t.Error("certificates from .pem and .p12 must be equal")
+	}
+}
+
+func TestCertificateFromPEMErrors(t *testing.T) {
+	names := []string{
+		"testdata/AuthKey_5MDQ4KLTY7.p8",
+		"testdata/AuthKeyBadPEM_1.p8",
+	}
+	for _, name := range names {
+		_, err := CertificateFromPEMFile(name)
+		if err != ErrCertificateBadPEM {
+			t.Errorf("%v: got: %v; want: ErrCertificateBadPEM", name, err)
+		}
+	}
+
+	data, err := os.ReadFile("testdata/Certificate.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CertificateFromPEMBytes(data[:len(data)/2])
+	if err != ErrCertificateBadPEM {
+		t.Errorf("got: %v; want: ErrCertificateBadPEM", err)
+	}
+}
+
+func TestCertificateTopics(t *testing.T) {
+	cert, err := CertificateFromPEMFile("testdata/Certificate.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"com.example.app",
+		"com.example.app.voip",
+		"com.example.app.complication",
+	}
+	got := CertificateTopics(cert.Leaf)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+
+	if !CertificateDevelopment(cert.Leaf) {
+		t.Error("certificate must support development server")
+	}
+	if !CertificateProduction(cert.Leaf) {
+		t.Error("certificate must support production server")
+	}
+}
@@ -0,0 +1,245 @@
+package apns
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+)
+
+// See https://developer.apple.com/documentation/usernotifications/sending-channel-management-requests-to-apns.
+
+// Channel management servers for broadcast push notifications.
+const (
+	// Development channel management server.
+	ChannelHostDevelopment = "https://api-manage-broadcast.sandbox.push.apple.com:2195"
+
+	// Production channel management server.
+	ChannelHostProduction = "https://api-manage-broadcast.push.apple.com:2196"
+)
+
+// Values for Channel.MessageStoragePolicy field.
+const (
+	// APNs doesn't store messages of the channel,
+	// devices that are offline miss them.
+	// Broadcast notifications to such a channel must have expiration 0.
+	MessageStoragePolicyNone = 0
+
+	// APNs stores the most recent message of the channel
+	// and delivers it when a device comes online.
+	MessageStoragePolicyMostRecent = 1
+)
+
+// Value for Channel.PushType field.
+const ChannelPushTypeLiveActivity = "LiveActivity"
+
+// The additional status codes of channel management responses.
+const (
+	// The channel was created.
+	Status201 = 201
+
+	// The channel was deleted.
+	Status204 = 204
+)
+
+// The additional error codes of channel management and broadcast responses.
+const (
+	// Status 400. The apns-channel-id value is invalid.
+	ReasonBadChannelId = "BadChannelId"
+
+	// Status 400. The apns-channel-id header of the request isn't specified and is required.
+	ReasonMissingChannelId = "MissingChannelId"
+
+	// Status 400. The message-storage-policy value is invalid.
+	ReasonBadMessageStoragePolicy = "BadMessageStoragePolicy"
+
+	// Status 400. The app reached the maximum number of channels.
+	ReasonCannotCreateChannelConfig = "CannotCreateChannelConfig"
+
+	// Status 404. The channel doesn't exist or was deleted.
+	ReasonChannelNotRegistered = "ChannelNotRegistered"
+)
+
+// Error values for the reasons of channel management responses.
+var (
+	ErrReasonBadChannelId              = &Error{Status: Status400, Reason: ReasonBadChannelId}
+	ErrReasonMissingChannelId          = &Error{Status: Status400, Reason: ReasonMissingChannelId}
+	ErrReasonBadMessageStoragePolicy   = &Error{Status: Status400, Reason: ReasonBadMessageStoragePolicy}
+	ErrReasonCannotCreateChannelConfig = &Error{Status: Status400, Reason: ReasonCannotCreateChannelConfig}
+	ErrReasonChannelNotRegistered      = &Error{Status: Status404, Reason: ReasonChannelNotRegistered}
+)
+
+// Channel is a broadcast push channel of an app.
+type Channel struct {
+	// The base64 encoded channel identifier assigned by APNs.
+	ID string `json:"-"` // header: apns-channel-id
+
+	// MessageStoragePolicyNone or MessageStoragePolicyMostRecent.
+	MessageStoragePolicy int `json:"message-storage-policy"`
+
+	// The push type of broadcast notifications, ChannelPushTypeLiveActivity.
+	PushType string `json:"push-type"`
+}
+
+// ChannelResponse for channel management request.
+type ChannelResponse struct {
+	// The same value found in the apns-request-id field of the request’s header.
+	// If you don’t specify it, APNs creates a new UUID and returns it in this header.
+	RequestID string // header: apns-request-id
+
+	// The HTTP status code.
+	Status int // header: :status
+
+	// The error code indicating the reason for the failure.
+	Reason string
+
+	// The identifier of the created or requested channel.
+	ChannelID string // header: apns-channel-id
+
+	// The channel configuration returned by ChannelClient.Get.
+	Channel *Channel
+
+	// The identifiers of all channels of the app returned by ChannelClient.List.
+	Channels []string
+}
+
+// ParseChannelResponse parses HTTP response r from channel management request.
+// Parses request header and JSON body.
+func ParseChannelResponse(r *http.Response) (*ChannelResponse, error) {
+	defer r.Body.Close()
+	res := &ChannelResponse{
+		RequestID: r.Header.Get("apns-request-id"),
+		Status:    r.StatusCode,
+		ChannelID: r.Header.Get("apns-channel-id"),
+	}
+
+	var body struct {
+		Reason               string   `json:"reason"`
+		Channels             []string `json:"channels"`
+		MessageStoragePolicy *int     `json:"message-storage-policy"`
+		PushType             string   `json:"push-type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	res.Reason = body.Reason
+	res.Channels = body.Channels
+	if body.MessageStoragePolicy != nil || body.PushType != "" {
+		res.Channel = &Channel{
+			ID:       res.ChannelID,
+			PushType: body.PushType,
+		}
+		if body.MessageStoragePolicy != nil {
+			res.Channel.MessageStoragePolicy = *body.MessageStoragePolicy
+		}
+	}
+	return res, nil
+}
+
+// Err returns *Error for response with failure status or nil.
+// The Error.ID is the apns-request-id of the request.
+func (r *ChannelResponse) Err() error {
+	switch r.Status {
+	case Status200, Status201, Status204:
+		return nil
+	}
+	return &Error{
+		ID:     r.RequestID,
+		Status: r.Status,
+		Reason: r.Reason,
+	}
+}
+
+// ChannelClient is a token-based client for managing broadcast push channels.
+type ChannelClient struct {
+	Token      *Token
+	HTTPClient *http.Client
+
+	// Channel management server.
+	// If Host field omitted ChannelHostProduction will be used.
+	Host string
+}
+
+// NewChannelClient creates channel management client with token and http.Client client,
+// pass nil for client to use http.DefaultClient.
+func NewChannelClient(token *Token, httpClient *http.Client) *ChannelClient {
+	return &ChannelClient{
+		Token:      token,
+		HTTPClient: httpClient,
+	}
+}
+
+// Create creates a new channel for the app with bundleID.
+// The ChannelID of the response identifies the new channel.
+func (c *ChannelClient) Create(ctx context.Context, bundleID string, ch *Channel) (*ChannelResponse, error) {
+	body, err := json.Marshal(ch)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodPost, channelsPath(bundleID), "", body)
+}
+
+// Get returns configuration of the channel with channelID in the Channel field of the response.
+func (c *ChannelClient) Get(ctx context.Context, bundleID, channelID string) (*ChannelResponse, error) {
+	return c.do(ctx, http.MethodGet, channelsPath(bundleID), channelID, nil)
+}
+
+// List returns identifiers of all channels of the app in the Channels field of the response.
+func (c *ChannelClient) List(ctx context.Context, bundleID string) (*ChannelResponse, error) {
+	return c.do(ctx, http.MethodGet, "/1/apps/"+url.PathEscape(bundleID)+"/all-channels", "", nil)
+}
+
+// Delete deletes the channel with channelID.
+func (c *ChannelClient) Delete(ctx context.Context, bundleID, channelID string) (*ChannelResponse, error) {
+	return c.do(ctx, http.MethodDelete, channelsPath(bundleID), channelID, nil)
+}
+
+func channelsPath(bundleID string) string {
+	return "/1/apps/" + url.PathEscape(bundleID) + "/channels"
+}
+
+func (c *ChannelClient) do(ctx context.Context, method, path, channelID string, body []byte) (*ChannelResponse, error) {
+	if c.Token == nil {
+		return nil, ErrClientTokenNil
+	}
+
+	host := c.Host
+	if host == "" {
+		host = ChannelHostProduction
+	}
+
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, host+path, r)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("apns-request-id", NewID())
+	if channelID != "" {
+		req.Header.Set("apns-channel-id", channelID)
+	}
+	if body != nil {
+		req.Header.Set("content-type", "application/json")
+	}
+	if err := c.Token.SetAuthorization(req.Header); err != nil {
+		return nil, err
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseChannelResponse(res)
+}
@@ -0,0 +1,181 @@
+package apns
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseChannelResponse(t *testing.T) {
+	r := &http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"Apns-Request-Id": []string{"EC1BF194-B3B2-424A-89A9-5A918A6E6B5D"},
+			"Apns-Channel-Id": []string{"dHN0LXNyY2gtY2hubA=="},
+		},
+		Body: io.NopCloser(strings.NewReader(`{"message-storage-policy":0,"push-type":"LiveActivity"}`)),
+	}
+	res, err := ParseChannelResponse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RequestID != "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D" {
+		t.Errorf("res.RequestID: %v", res.RequestID)
+	}
+	want := Channel{ID: "dHN0LXNyY2gtY2hubA==", MessageStoragePolicy: MessageStoragePolicyNone, PushType: ChannelPushTypeLiveActivity}
+	if res.Channel == nil || *res.Channel != want {
+		t.Errorf("res.Channel: %v; want: %v", res.Channel, want)
+	}
+	if res.Err() != nil {
+		t.Errorf("res.Err: %v; want nil", res.Err())
+	}
+
+	r = &http.Response{
+		StatusCode: 404,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(`{"reason":"ChannelNotRegistered"}`)),
+	}
+	res, err = ParseChannelResponse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Channel != nil {
+		t.Errorf("res.Channel: %v; want nil", res.Channel)
+	}
+	if !errors.Is(res.Err(), ErrReasonChannelNotRegistered) {
+		t.Errorf("res.Err: %v; want: ErrReasonChannelNotRegistered", res.Err())
+	}
+
+	r = &http.Response{
+		StatusCode: 400,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader("Hello")),
+	}
+	if _, err = ParseChannelResponse(r); err == nil {
+		t.Error("err must be not nil")
+	}
+}
+
+func TestChannelClient(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+
+	channels := map[string]Channel{}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("authorization") != "bearer "+token.Bearer {
+			t.Errorf("authorization: %v", r.Header.Get("authorization"))
+		}
+		if r.Header.Get("apns-request-id") == "" {
+			t.Error("apns-request-id must be set")
+		}
+		w.Header().Set("apns-request-id", r.Header.Get("apns-request-id"))
+
+		id := r.Header.Get("apns-channel-id")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/1/apps/com.example.app/channels":
+			var ch Channel
+			json.NewDecoder(r.Body).Decode(&ch)
+			if ch.MessageStoragePolicy > MessageStoragePolicyMostRecent {
+				w.WriteHeader(Status400)
+				w.Write([]byte(`{"reason":"BadMessageStoragePolicy"}`))
+				return
+			}
+			channels["dHN0LXNyY2gtY2hubA=="] = ch
+			w.Header().Set("apns-channel-id", "dHN0LXNyY2gtY2hubA==")
+			w.WriteHeader(Status201)
+		case r.Method == http.MethodGet && r.URL.Path == "/1/apps/com.example.app/channels":
+			ch, ok := channels[id]
+			if !ok {
+				w.WriteHeader(Status404)
+				w.Write([]byte(`{"reason":"ChannelNotRegistered"}`))
+				return
+			}
+			w.Header().Set("apns-channel-id", id)
+			json.NewEncoder(w).Encode(ch)
+		case r.Method == http.MethodGet && r.URL.Path == "/1/apps/com.example.app/all-channels":
+			ids := []string{}
+			for id := range channels {
+				ids = append(ids, id)
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"channels": ids})
+		case r.Method == http.MethodDelete && r.URL.Path == "/1/apps/com.example.app/channels":
+			delete(channels, id)
+			w.WriteHeader(Status204)
+		default:
+			w.WriteHeader(Status404)
+			w.Write([]byte(`{"reason":"BadPath"}`))
+		}
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewChannelClient(token, ts.Client())
+	client.Host = ts.URL
+	ctx := context.Background()
+
+	res, err := client.Create(ctx, "com.example.app", &Channel{
+		MessageStoragePolicy: MessageStoragePolicyMostRecent,
+		PushType:             ChannelPushTypeLiveActivity,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != Status201 || res.ChannelID != "dHN0LXNyY2gtY2hubA==" || res.RequestID == "" {
+		t.Errorf("create: %+v", res)
+	}
+
+	res, err = client.Get(ctx, "com.example.app", "dHN0LXNyY2gtY2hubA==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Channel{ID: "dHN0LXNyY2gtY2hubA==", MessageStoragePolicy: MessageStoragePolicyMostRecent, PushType: ChannelPushTypeLiveActivity}
+	if res.Channel == nil || *res.Channel != want {
+		t.Errorf("get: %v; want: %v", res.Channel, want)
+	}
+
+	res, err = client.List(ctx, "com.example.app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Channels) != 1 || res.Channels[0] != "dHN0LXNyY2gtY2hubA==" {
+		t.Errorf("list: %v", res.Channels)
+	}
+
+	res, err = client.Delete(ctx, "com.example.app", "dHN0LXNyY2gtY2hubA==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != Status204 || res.Err() != nil {
+		t.Errorf("delete: %+v", res)
+	}
+
+	res, err = client.Get(ctx, "com.example.app", "dHN0LXNyY2gtY2hubA==")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(res.Err(), ErrReasonChannelNotRegistered) {
+		t.Errorf("get deleted: %v; want: ErrReasonChannelNotRegistered", res.Err())
+	}
+
+	res, err = client.Create(ctx, "com.example.app", &Channel{MessageStoragePolicy: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(res.Err(), ErrReasonBadMessageStoragePolicy) {
+		t.Errorf("create: %v; want: ErrReasonBadMessageStoragePolicy", res.Err())
+	}
+
+	client.Token = nil
+	if _, err = client.List(ctx, "com.example.app"); err != ErrClientTokenNil {
+		t.Errorf("err: %v; want: ErrClientTokenNil", err)
+	}
+}
@@ -2,8 +2,11 @@ package apns
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"net/http"
+	"strings"
+	"sync"
 )
 
 var (
@@ -11,10 +14,43 @@ var (
 	ErrClientTokenNil        = errors.New("client: token is nil")
 )
 
-// Token-based client for sending remote notifications.
+// Token-based or certificate-based client for sending remote notifications.
 type Client struct {
-	Token      *Token
+	// Token for token-based connection.
+	// If Token is set, every request is signed by its bearer.
+	Token *Token
+
+	// Provider of tokens for token-based connection used instead of Token if it is set,
+	// for example, RotatingToken for switching keys without downtime.
+	// If APNs rejects the provided token as invalid, the provider is invoked
+	// and the request is sent once again if it has another token.
+	TokenProvider TokenProvider
+
+	// Provider certificate for certificate-based connection.
+	// Used only when Token and TokenProvider are nil, requests are sent without authorization header
+	// and HTTPClient must present this certificate, see NewCertificateClient.
+	// If HTTPClient is nil, client created by CertificateHTTPClient is used.
+	Certificate *tls.Certificate
+
+	// HTTP/2 client for requests, nil means http.DefaultClient
+	// or client presenting Certificate.
 	HTTPClient *http.Client
+
+	// Policy for sending failed requests again, nil disables retries.
+	// See Backoff for exponential backoff of retryable responses and errors.
+	RetryPolicy RetryPolicy
+
+	// Store invoked with device tokens APNs reports as invalid,
+	// nil disables the invocation. See DeviceTokenStore.
+	DeviceTokenStore DeviceTokenStore
+
+	// If true, notifications are checked by Notification.Validate
+	// and not sent if they are invalid.
+	Validate bool
+
+	mu         sync.Mutex
+	certClient *http.Client     // client presenting Certificate when HTTPClient is nil
+	certFor    *tls.Certificate // Certificate certClient presents
 }
 
 // NewClient creates client with token and http.Client client,
@@ -26,40 +62,178 @@ func NewClient(token *Token, httpClient *http.Client) *Client {
 	}
 }
 
+// NewCertificateClient creates client for certificate-based connection
+// with HTTP/2 client presenting cert.
+func NewCertificateClient(cert tls.Certificate) *Client {
+	return &Client{
+		Certificate: &cert,
+		HTTPClient:  CertificateHTTPClient(cert),
+	}
+}
+
 // Push sends remote notification.
 func (c *Client) Push(n *Notification) (*Response, error) {
 	return c.PushWithContext(context.Background(), n)
 }
 
 // Push sends remote notification with context.
+// If APNs rejects the provider token as expired,
+// the token is regenerated and the request is sent once again.
+// If APNs rejects the token of TokenProvider as invalid,
+// the request is sent once again with the next token of the provider.
+// Response.KeyID reports the key ID of the token signed the request.
+// If RetryPolicy is set, failed requests are sent again with the same apns-id
+// until the policy stops, ctx is done or its deadline comes;
+// the last response or error is returned.
+// If DeviceTokenStore is set and APNs reports the device token as invalid,
+// the store is invoked and its error is returned along with the response.
 func (c *Client) PushWithContext(ctx context.Context, n *Notification) (*Response, error) {
+	res, err := c.push(ctx, n)
+	if err != nil || c.DeviceTokenStore == nil {
+		return res, err
+	}
+	if t, ok := invalidDeviceToken(n, res); ok {
+		return res, c.DeviceTokenStore.Invalidate(t)
+	}
+	return res, nil
+}
+
+// push sends notification with token refresh and retries.
+func (c *Client) push(ctx context.Context, n *Notification) (*Response, error) {
 	if n == nil {
 		return nil, ErrClientNotificationNil
 	}
 
-	req, err := n.BuildRequestWithContext(ctx)
-	if err != nil {
-		return nil, err
+	if c.Validate {
+		if err := n.Validate(); err != nil {
+			return nil, err
+		}
 	}
 
-	if c.Token == nil {
-		return nil, ErrClientTokenNil
+	if c.RetryPolicy != nil && n.ID == "" {
+		// APNs identifies retries of the same notification by apns-id.
+		withID := *n
+		withID.ID = NewID()
+		n = &withID
+	}
+
+	refreshed, switched := false, false
+	for attempt := 1; ; attempt++ {
+		token := c.token()
+		req, err := c.buildRequest(ctx, n, token)
+		if err != nil {
+			return nil, err
+		}
+
+		res, err := c.do(req)
+		if res != nil && token != nil {
+			res.KeyID = token.KeyID
+		}
+		if !refreshed && c.refreshToken(token, req, res) {
+			// Resend once with regenerated token, it is not a retry.
+			refreshed = true
+			attempt--
+			continue
+		}
+		if !switched && c.switchToken(token, res) {
+			// Resend once with another token, it is not a retry.
+			switched = true
+			attempt--
+			continue
+		}
+		if c.RetryPolicy == nil {
+			return res, err
+		}
+		delay, ok := c.RetryPolicy.Retry(attempt, res, err)
+		if !ok || !sleep(ctx, delay) {
+			return res, err
+		}
 	}
+}
+
+// token returns token for signing the next request,
+// nil for certificate-based connection.
+func (c *Client) token() *Token {
+	if c.TokenProvider != nil {
+		return c.TokenProvider.Token()
+	}
+	return c.Token
+}
+
+// refreshToken regenerates token if APNs rejected bearer of req as expired.
+// Reports whether the request can be sent again with the new bearer.
+func (c *Client) refreshToken(token *Token, req *http.Request, res *Response) bool {
+	if token == nil || res == nil {
+		return false
+	}
+	if res.Status != Status403 || res.Reason != ReasonExpiredProviderToken {
+		return false
+	}
+	bearer := strings.TrimPrefix(req.Header.Get("authorization"), "bearer ")
+	_, err := token.Refresh(bearer)
+	return err == nil
+}
+
+// switchToken invokes TokenProvider if APNs rejected token as invalid.
+// Reports whether the request can be sent again with another token.
+func (c *Client) switchToken(token *Token, res *Response) bool {
+	if c.TokenProvider == nil || token == nil || res == nil {
+		return false
+	}
+	if res.Status != Status403 || res.Reason != ReasonInvalidProviderToken {
+		return false
+	}
+	return c.TokenProvider.Invalid(token)
+}
 
-	err = c.Token.SetAuthorization(req.Header)
+// buildRequest builds notification request and sets its authorization by token.
+func (c *Client) buildRequest(ctx context.Context, n *Notification, token *Token) (*http.Request, error) {
+	req, err := n.BuildRequestWithContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	httpClient := c.HTTPClient
-	if httpClient == nil {
-		httpClient = http.DefaultClient
+	if token != nil {
+		err = token.SetAuthorization(req.Header)
+		if err != nil {
+			return nil, err
+		}
+	} else if c.Certificate == nil {
+		return nil, ErrClientTokenNil
 	}
 
-	res, err := httpClient.Do(req)
+	return req, nil
+}
+
+// do sends notification request and parses its response.
+func (c *Client) do(req *http.Request) (*Response, error) {
+	res, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	return ParseResponse(res)
 }
+
+// httpClient returns HTTPClient, client presenting Certificate if HTTPClient is nil,
+// or http.DefaultClient.
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	if c.Certificate == nil {
+		return http.DefaultClient
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Certificate can be replaced, the client presenting the previous one is dropped.
+	if c.certClient == nil || c.certFor != c.Certificate {
+		if c.certClient != nil {
+			c.certClient.CloseIdleConnections()
+		}
+		c.certClient = CertificateHTTPClient(*c.Certificate)
+		c.certFor = c.Certificate
+	}
+	return c.certClient
+}
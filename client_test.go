@@ -4,10 +4,13 @@ import (
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestClientPush(t *testing.T) {
@@ -84,6 +87,206 @@ func TestClientPush(t *testing.T) {
 	}
 }
 
+func TestClientPushCertificate(t *testing.T) {
+	cert, err := CertificateFromP12File("testdata/Certificate.p12", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || !r.TLS.PeerCertificates[0].Equal(cert.Leaf) {
+			t.Error("client must present provider certificate")
+		}
+		if r.Header.Get("authorization") != "" {
+			t.Errorf("authorization: %v; want empty", r.Header.Get("authorization"))
+		}
+		if r.ProtoMajor != 2 {
+			t.Errorf("proto: %v; want: HTTP/2", r.Proto)
+		}
+		w.Header().Set("apns-id", "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D")
+	}))
+	ts.EnableHTTP2 = true
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewCertificateClient(cert)
+	if client.Token != nil {
+		t.Error("client.Token must be nil")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	client.HTTPClient.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+
+	n := &Notification{
+		DeviceToken: "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e",
+		Host:        ts.URL,
+		Topic:       "com.example.app",
+		Payload:     `{"aps":{"alert":"Hello"}}`,
+	}
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != Status200 {
+		t.Errorf("res.Status: %v; want: %v", res.Status, Status200)
+	}
+}
+
+func TestClientPushCertificateWithoutHTTPClient(t *testing.T) {
+	cert, err := CertificateFromP12File("testdata/Certificate.p12", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 || !r.TLS.PeerCertificates[0].Equal(cert.Leaf) {
+			t.Error("client must present provider certificate")
+		}
+		w.Header().Set("apns-id", "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D")
+	}))
+	ts.EnableHTTP2 = true
+	ts.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := &Client{Certificate: &cert}
+	httpClient := client.httpClient()
+	if httpClient == http.DefaultClient {
+		t.Fatal("client presenting certificate must be used")
+	}
+	if client.httpClient() != httpClient {
+		t.Error("client presenting certificate must be reused")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ts.Certificate())
+	httpClient.Transport.(*http.Transport).TLSClientConfig.RootCAs = roots
+
+	res, err := client.Push(&Notification{
+		DeviceToken: "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e",
+		Host:        ts.URL,
+		Topic:       "com.example.app",
+		Payload:     `{"aps":{"alert":"Hello"}}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != Status200 {
+		t.Errorf("res.Status: %v; want: %v", res.Status, Status200)
+	}
+
+	other := cert
+	client.Certificate = &other
+	if client.httpClient() == httpClient {
+		t.Error("client must be created for replaced certificate")
+	}
+}
+
+func TestClientPushExpiredProviderToken(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bearers []string
+	expired := ""
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer := r.Header.Get("authorization")
+		bearers = append(bearers, bearer)
+		if bearer == "bearer "+expired {
+			w.WriteHeader(Status403)
+			w.Write([]byte(`{"reason":"ExpiredProviderToken"}`))
+		}
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	token := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	client := NewClient(token, ts.Client())
+	n := &Notification{Host: ts.URL, Payload: `{}`}
+
+	// Local clock thinks the bearer is fresh, but APNs does not.
+	token.IssuedAt = time.Now().Add(-(TokenMinRefreshInterval + 60) * time.Second).Unix()
+	token.Bearer, err = GenerateBearer(key, token.KeyID, token.TeamID, token.IssuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired = token.Bearer
+
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != Status200 {
+		t.Errorf("res.Status: %v; want: %v", res.Status, Status200)
+	}
+	if len(bearers) != 2 || bearers[1] == bearers[0] {
+		t.Errorf("request must be resent once with new bearer: %v", bearers)
+	}
+
+	// Too soon to regenerate the bearer again.
+	bearers = nil
+	expired = token.Bearer
+	res, err = client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != ReasonExpiredProviderToken {
+		t.Errorf("res.Reason: %v; want: %v", res.Reason, ReasonExpiredProviderToken)
+	}
+	if len(bearers) != 1 {
+		t.Errorf("requests: %v; want: 1", len(bearers))
+	}
+}
+
+func TestClientPushBroadcast(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/4/broadcasts/apps/com.example.app" {
+			t.Errorf(":path: %v; want: %v", r.URL.Path, "/4/broadcasts/apps/com.example.app")
+		}
+		if r.Header.Get("apns-channel-id") != "dHN0LXNyY2gtY2hubA==" {
+			t.Errorf("apns-channel-id: %v", r.Header.Get("apns-channel-id"))
+		}
+		if r.Header.Get("apns-expiration") != "0" {
+			t.Errorf("apns-expiration: %v; want: 0", r.Header.Get("apns-expiration"))
+		}
+		w.Header().Set("apns-request-id", "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D")
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewClient(NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"), ts.Client())
+	client.Validate = true
+	client.DeviceTokenStore = failingDeviceTokenStore{}
+
+	n := &Notification{
+		Host:     ts.URL,
+		Topic:    "com.example.app.push-type.liveactivity",
+		PushType: PushTypeLiveActivity,
+		Payload: BuildPayload(&APS{
+			Timestamp:    1629000000,
+			Event:        LiveActivityEventUpdate,
+			ContentState: map[string]interface{}{"score": 1},
+		}, nil),
+	}
+	n.SetChannel(&Channel{ID: "dHN0LXNyY2gtY2hubA==", MessageStoragePolicy: MessageStoragePolicyNone})
+
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != Status200 || res.RequestID != "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D" {
+		t.Errorf("res: %+v", res)
+	}
+}
+
 func TestClientPushErrors(t *testing.T) {
 	client := NewClient(nil, nil)
 	_, err := client.Push(nil)
@@ -0,0 +1,25 @@
+package apns
+
+import "time"
+
+// Clock provides the current time for Token,
+// so tests can check refresh timing and simulate clock skew without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is a function used as Clock.
+type ClockFunc func() time.Time
+
+// Now implements Clock.
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// now returns the current time of clock, the system time if clock is nil.
+func now(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock.Now()
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+
+	"github.com/bergusman/apns-go"
+)
+
+const bulkUsage = `Usage:
+
+	apns bulk [flags] <notifications.jsonl>
+
+Sends notifications of the JSON Lines file, - for stdin, concurrently
+and writes a result per line to -results in JSON Lines format.
+Each line of the file is a notification:
+
+	{"device_token":"...","headers":{"apns-topic":"com.example.app"},"payload":{"aps":{"alert":"Hi"}}}
+
+Supported headers are apns-id, apns-topic, apns-push-type, apns-priority,
+apns-expiration and apns-collapse-id.
+With -resume, lines already present in -results are skipped,
+so an interrupted run can be continued.
+
+Flags:
+`
+
+// Maximum size of a line of notifications file.
+const maxLineSize = 1 << 20
+
+// bulkLine is a notification line of notifications file.
+type bulkLine struct {
+	DeviceToken string            `json:"device_token"`
+	Headers     map[string]string `json:"headers"`
+	Payload     json.RawMessage   `json:"payload"`
+}
+
+// notification returns notification of the line with topic used if apns-topic is omitted.
+func (l *bulkLine) notification(topic string) (*apns.Notification, error) {
+	n := &apns.Notification{
+		DeviceToken: l.DeviceToken,
+		Topic:       topic,
+		Payload:     []byte(l.Payload),
+	}
+	if len(l.Payload) == 0 {
+		return nil, errors.New("payload is required")
+	}
+	for k, v := range l.Headers {
+		switch k {
+		case "apns-id":
+			n.ID = v
+		case "apns-topic":
+			n.Topic = v
+		case "apns-push-type":
+			n.PushType = v
+		case "apns-priority":
+			p, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid apns-priority %q", v)
+			}
+			n.Priority = p
+		case "apns-expiration":
+			n.Expiration = v
+		case "apns-collapse-id":
+			n.CollapseID = v
+		default:
+			return nil, fmt.Errorf("unsupported header %q", k)
+		}
+	}
+	return n, nil
+}
+
+func runBulk(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("bulk", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, bulkUsage)
+		fs.PrintDefaults()
+	}
+
+	var conn connFlags
+	conn.register(fs)
+	topic := fs.String("topic", "", "topic of notifications without apns-topic header")
+	workers := fs.Int("workers", apns.DefaultWorkers, "number of concurrent requests")
+	resultsName := fs.String("results", "-", "`path` to results file, - for stdout")
+	resume := fs.Bool("resume", false, "skip lines present in -results file and append to it")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "apns bulk: notifications file is required")
+		fs.Usage()
+		return 2
+	}
+	if *resume && *resultsName == "-" {
+		fmt.Fprintln(stderr, "apns bulk: -resume requires -results file")
+		return 2
+	}
+
+	host, err := conn.Host()
+	if err != nil {
+		fmt.Fprintf(stderr, "apns bulk: %v\n", err)
+		return 2
+	}
+	client, err := conn.Client()
+	if err != nil {
+		fmt.Fprintf(stderr, "apns bulk: %v\n", err)
+		return 1
+	}
+	client.Validate = true
+
+	var in io.Reader = stdin
+	if name := fs.Arg(0); name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			fmt.Fprintf(stderr, "apns bulk: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var processed map[int]bool
+	out := stdout
+	if *resultsName != "-" {
+		if *resume {
+			processed, err = readProcessed(*resultsName)
+			if err != nil {
+				fmt.Fprintf(stderr, "apns bulk: %v\n", err)
+				return 1
+			}
+		}
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if *resume {
+			flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+		}
+		f, err := os.OpenFile(*resultsName, flags, 0644)
+		if err != nil {
+			fmt.Fprintf(stderr, "apns bulk: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	b := &bulk{
+		client:    client,
+		host:      host,
+		topic:     *topic,
+		processed: processed,
+		lines:     make(map[*apns.Notification]int),
+	}
+	sent, failed, err := b.run(ctx, in, out, *workers)
+	fmt.Fprintf(stderr, "apns bulk: sent: %d, failed: %d\n", sent, failed)
+	if err != nil {
+		fmt.Fprintf(stderr, "apns bulk: %v\n", err)
+		return 1
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// bulk sends notifications of a file.
+type bulk struct {
+	client    *apns.Client
+	host      string
+	topic     string
+	processed map[int]bool
+
+	mu    sync.Mutex
+	lines map[*apns.Notification]int
+}
+
+// run sends notifications read from in and writes their results to out.
+// Returns the numbers of accepted and failed notifications.
+func (b *bulk) run(ctx context.Context, in io.Reader, out io.Writer, workers int) (sent, failed int, err error) {
+	ns := make(chan *apns.Notification)
+	invalid := make(chan *result)
+	var scanErr error
+	go func() {
+		defer close(ns)
+		defer close(invalid)
+		scanErr = b.read(ctx, in, ns, invalid)
+	}()
+
+	results := b.client.PushAll(ctx, ns, workers)
+	for results != nil || invalid != nil {
+		var r *result
+		select {
+		case res, ok := <-results:
+			if !ok {
+				results = nil
+				continue
+			}
+			b.mu.Lock()
+			line := b.lines[res.Notification]
+			delete(b.lines, res.Notification)
+			b.mu.Unlock()
+			if ctx.Err() != nil && errors.Is(res.Err, ctx.Err()) {
+				// Not sent because of interruption, it is sent again on resume.
+				continue
+			}
+			r = newResult(res.Notification, res.Response, res.Err)
+			r.Line = line
+		case res, ok := <-invalid:
+			if !ok {
+				invalid = nil
+				continue
+			}
+			r = res
+		}
+
+		if r.ok() {
+			sent++
+		} else {
+			failed++
+		}
+		line, err := json.Marshal(r)
+		if err != nil {
+			return sent, failed, err
+		}
+		if _, err := out.Write(append(line, '\n')); err != nil {
+			return sent, failed, err
+		}
+	}
+
+	if scanErr != nil {
+		return sent, failed, scanErr
+	}
+	return sent, failed, ctx.Err()
+}
+
+// read parses notifications of in and sends them to ns,
+// results for lines that can't be parsed are sent to invalid.
+func (b *bulk) read(ctx context.Context, in io.Reader, ns chan<- *apns.Notification, invalid chan<- *result) error {
+	sc := bufio.NewScanner(in)
+	sc.Buffer(make([]byte, 64*1024), maxLineSize)
+	for i := 1; sc.Scan(); i++ {
+		if b.processed[i] || len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+
+		var l bulkLine
+		var n *apns.Notification
+		err := json.Unmarshal(sc.Bytes(), &l)
+		if err == nil {
+			n, err = l.notification(b.topic)
+		}
+		if err != nil {
+			select {
+			case invalid <- &result{Line: i, DeviceToken: l.DeviceToken, Error: err.Error()}:
+				continue
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		n.Host = b.host
+		b.mu.Lock()
+		b.lines[n] = i
+		b.mu.Unlock()
+		select {
+		case ns <- n:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return sc.Err()
+}
+
+// readProcessed returns line numbers of results in the named results file.
+// A missing file has no results, a broken last line is left by interrupted run
+// and is terminated to append next results after it.
+func readProcessed(name string) (map[int]bool, error) {
+	processed := make(map[int]bool)
+	data, err := os.ReadFile(name)
+	if os.IsNotExist(err) {
+		return processed, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range bytes.Split(data, []byte{'\n'}) {
+		var r result
+		if json.Unmarshal(line, &r) == nil && r.Line > 0 {
+			processed[r.Line] = true
+		}
+	}
+
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, err
+		}
+		_, err = f.Write([]byte{'\n'})
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return processed, nil
+}
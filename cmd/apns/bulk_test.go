@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+func writeNotifications(t *testing.T, lines ...string) string {
+	name := filepath.Join(t.TempDir(), "notifications.jsonl")
+	if err := os.WriteFile(name, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func readResults(t *testing.T, data []byte) map[int]result {
+	results := make(map[int]result)
+	for _, line := range bytes.Split(bytes.TrimSpace(data), []byte{'\n'}) {
+		var r result
+		if err := json.Unmarshal(line, &r); err != nil {
+			t.Fatalf("%s: %v", line, err)
+		}
+		if _, ok := results[r.Line]; ok {
+			t.Errorf("duplicate result for line %v", r.Line)
+		}
+		results[r.Line] = r
+	}
+	return results
+}
+
+func TestBulk(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	unregistered := strings.Repeat("b", 64)
+	s.Unregister(unregistered, time.Unix(1629000000, 0))
+
+	name := writeNotifications(t,
+		`{"device_token":"`+deviceToken+`","headers":{"apns-push-type":"background","apns-priority":"5"},"payload":{"aps":{"content-available":1}}}`,
+		`{"device_token":"`+unregistered+`","payload":{"aps":{"alert":"Hi"}}}`,
+		`{"device_token":`,
+		``,
+		`{"device_token":"`+deviceToken+`","headers":{"apns-unknown":"x"},"payload":{}}`,
+		`{"device_token":"`+deviceToken+`","headers":{"apns-topic":"com.example.other"},"payload":{"aps":{"alert":"Hi"}}}`,
+	)
+
+	args := append([]string{"bulk"}, connArgs(s)...)
+	args = append(args, "-topic", "com.example.app", "-workers", "2", name)
+	var stdout, stderr bytes.Buffer
+	if code := run(args, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("code: %v; want: 1; stderr: %v", code, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "sent: 2, failed: 3") {
+		t.Errorf("stderr: %v", stderr.String())
+	}
+
+	results := readResults(t, stdout.Bytes())
+	if r := results[1]; !r.ok() || r.ID == "" || r.DeviceToken != deviceToken {
+		t.Errorf("line 1: %+v", r)
+	}
+	if r := results[2]; r.Status != apns.Status410 || r.Reason != apns.ReasonUnregistered || r.Timestamp != 1629000000000 {
+		t.Errorf("line 2: %+v", r)
+	}
+	if r := results[3]; r.Error == "" {
+		t.Errorf("line 3: %+v", r)
+	}
+	if _, ok := results[4]; ok {
+		t.Error("blank line 4 must be skipped")
+	}
+	if r := results[5]; !strings.Contains(r.Error, "unsupported header") {
+		t.Errorf("line 5: %+v", r)
+	}
+	if r := results[6]; !r.ok() {
+		t.Errorf("line 6: %+v", r)
+	}
+
+	topics := make(map[string]bool)
+	for _, p := range s.Pushes() {
+		topics[p.Notification.Topic] = true
+	}
+	if len(s.Pushes()) != 3 || !topics["com.example.app"] || !topics["com.example.other"] {
+		t.Errorf("pushes: %v, topics: %v", len(s.Pushes()), topics)
+	}
+}
+
+func TestBulkResume(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	var lines []string
+	for i := 0; i < 5; i++ {
+		lines = append(lines, `{"device_token":"`+strings.Repeat(string(rune('a'+i)), 64)+`","payload":{"aps":{"alert":"Hi"}}}`)
+	}
+	name := writeNotifications(t, lines...)
+
+	// Interrupted run processed lines 2 and 4 and was killed writing a result.
+	resultsName := filepath.Join(t.TempDir(), "results.jsonl")
+	err := os.WriteFile(resultsName, []byte(`{"line":2,"status":200}`+"\n"+`{"line":4,"status":200}`+"\n"+`{"line":5,"sta`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args := append([]string{"bulk"}, connArgs(s)...)
+	args = append(args, "-topic", "com.example.app", "-results", resultsName, "-resume", name)
+	var stdout, stderr bytes.Buffer
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("code: %v; stderr: %v", code, stderr.String())
+	}
+
+	var sent []string
+	for _, p := range s.Pushes() {
+		sent = append(sent, p.Notification.DeviceToken[:1])
+	}
+	sort.Strings(sent)
+	if strings.Join(sent, "") != "ace" {
+		t.Errorf("sent: %v; want: [a c e]", sent)
+	}
+
+	data, err := os.ReadFile(resultsName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Broken line is left as is.
+	data = bytes.Replace(data, []byte(`{"line":5,"sta`+"\n"), nil, 1)
+	results := readResults(t, data)
+	for i := 1; i <= 5; i++ {
+		if _, ok := results[i]; !ok {
+			t.Errorf("no result for line %v", i)
+		}
+	}
+}
+
+func TestBulkErrors(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	tests := []struct {
+		args []string
+		code int
+		err  string
+	}{
+		{nil, 2, "notifications file is required"},
+		{[]string{"-resume", "-"}, 2, "-resume requires -results file"},
+		{[]string{filepath.Join(t.TempDir(), "missing.jsonl")}, 1, "no such file"},
+	}
+	for _, test := range tests {
+		args := append([]string{"bulk"}, connArgs(s)...)
+		args = append(args, test.args...)
+		var stdout, stderr bytes.Buffer
+		code := run(args, strings.NewReader(""), &stdout, &stderr)
+		if code != test.code {
+			t.Errorf("%v: code: %v; want: %v", test.args, code, test.code)
+		}
+		if !strings.Contains(stderr.String(), test.err) {
+			t.Errorf("%v: stderr: %q; want: %q", test.args, stderr.String(), test.err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+// connFlags are flags of the token-based connection to APNs.
+type connFlags struct {
+	key      string
+	keyID    string
+	teamID   string
+	env      string
+	port2197 bool
+	host     string
+	insecure bool
+}
+
+func (f *connFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.key, "key", "", "`path` to .p8 authentication token signing key")
+	fs.StringVar(&f.keyID, "key-id", "", "key ID of the signing key")
+	fs.StringVar(&f.teamID, "team-id", "", "team ID of the developer account")
+	fs.StringVar(&f.env, "env", "development", "APNs environment: development or production")
+	fs.BoolVar(&f.port2197, "port2197", false, "connect to port 2197 instead of 443")
+	fs.StringVar(&f.host, "host", "", "APNs `URL` instead of -env, for example, of apns serve")
+	fs.BoolVar(&f.insecure, "insecure", false, "skip TLS certificate verification of -host")
+}
+
+// Host returns APNs host for the flags.
+func (f *connFlags) Host() (string, error) {
+	if f.host != "" {
+		return f.host, nil
+	}
+	switch f.env {
+	case "development", "dev", "sandbox":
+		if f.port2197 {
+			return apns.HostDevelopmentPort2197, nil
+		}
+		return apns.HostDevelopment, nil
+	case "production", "prod":
+		if f.port2197 {
+			return apns.HostProductionPort2197, nil
+		}
+		return apns.HostProduction, nil
+	}
+	return "", fmt.Errorf("unknown environment %q", f.env)
+}
+
+// Token loads the signing key and returns token for the flags.
+func (f *connFlags) Token() (*apns.Token, error) {
+	if f.key == "" || f.keyID == "" || f.teamID == "" {
+		return nil, errors.New("-key, -key-id and -team-id are required")
+	}
+	key, err := apns.AuthKeyFromFile(f.key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.key, err)
+	}
+	return apns.NewToken(key, f.keyID, f.teamID), nil
+}
+
+// Client returns token-based client for the flags.
+func (f *connFlags) Client() (*apns.Client, error) {
+	token, err := f.Token()
+	if err != nil {
+		return nil, err
+	}
+	return apns.NewClient(token, &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: f.insecure},
+			ForceAttemptHTTP2: true,
+		},
+	}), nil
+}
+
+// setExpiration sets expiration of n from s:
+// UNIX epoch in seconds, 0 for delivering only once or duration from now, for example, 1h.
+func setExpiration(n *apns.Notification, s string) error {
+	if s == "" {
+		return nil
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		n.Expiration = s
+		return nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid expiration %q: want UNIX epoch in seconds or duration", s)
+	}
+	n.SetExpirationAfter(d, nil)
+	return nil
+}
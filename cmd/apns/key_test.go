@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestKeyCheck(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"key", "check", keyFile}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("code: %v; stderr: %v", code, stderr.String())
+	}
+	want := "key: valid ECDSA P-256 private key\n" +
+		"key-id: 5MDQ4KLTY7 (from file name)\n" +
+		"fingerprint: SHA256:3da573ea48293284e7b8bdb7a1f4f03dfb6d74b750292d958241180c98fad150\n"
+	if stdout.String() != want {
+		t.Errorf("stdout: %q; want: %q", stdout.String(), want)
+	}
+}
+
+func TestKeyCheckErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"../../testdata/AuthKeyBadPEM_1.p8", "not PEM encoded"},
+		{"../../testdata/AuthKeyBadPKCS8_1.p8", "openssl pkcs8 -topk8"},
+		{"../../testdata/AuthKeyRSA.p8", "not an ECDSA P-256 key"},
+		{"../../testdata/AuthKeyECDSAP224.p8", "not an ECDSA P-256 key"},
+		{"../../testdata/missing.p8", "no such file"},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run([]string{"key", "check", test.name}, nil, &stdout, &stderr); code != 1 {
+			t.Errorf("%v: code: %v; want: 1", test.name, code)
+		}
+		if !strings.Contains(stderr.String(), test.want) {
+			t.Errorf("%v: stderr: %q; want: %q", test.name, stderr.String(), test.want)
+		}
+	}
+}
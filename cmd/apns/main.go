@@ -0,0 +1,69 @@
+// Command apns sends remote notifications to APNs.
+//
+// Usage:
+//
+//	apns <command> [flags]
+//
+// The commands are:
+//
+//	send    send a notification to device tokens
+//	bulk    send notifications of a JSON Lines file
+//	token   generate and decode provider tokens
+//	key     check .p8 authentication token signing keys
+//	serve   run a fake APNs server
+//
+// Run apns <command> -h for flags of the command.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `Usage:
+
+	apns <command> [flags]
+
+The commands are:
+
+	send    send a notification to device tokens
+	bulk    send notifications of a JSON Lines file
+	token   generate and decode provider tokens
+	key     check .p8 authentication token signing keys
+	serve   run a fake APNs server
+
+Run apns <command> -h for flags of the command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run runs the command of args and returns the exit code:
+// 0 on success, 1 on failure and 2 on invalid usage.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	switch args[0] {
+	case "send":
+		return runSend(args[1:], stdin, stdout, stderr)
+	case "bulk":
+		return runBulk(args[1:], stdin, stdout, stderr)
+	case "token":
+		return runToken(args[1:], stdin, stdout, stderr)
+	case "key":
+		return runKey(args[1:], stdout, stderr)
+	case "serve":
+		return runServe(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "apns: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{nil, 2},
+		{[]string{"help"}, 0},
+		{[]string{"unknown"}, 2},
+	}
+	for _, test := range tests {
+		var stdout, stderr bytes.Buffer
+		code := run(test.args, nil, &stdout, &stderr)
+		if code != test.code {
+			t.Errorf("%v: code: %v; want: %v", test.args, code, test.code)
+		}
+		if !strings.Contains(stdout.String()+stderr.String(), "Usage:") {
+			t.Errorf("%v: usage must be printed", test.args)
+		}
+	}
+}
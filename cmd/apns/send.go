@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+const sendUsage = `Usage:
+
+	apns send [flags] <device token>...
+
+Sends the notification to each device token and prints the responses.
+The payload is taken from -payload or -payload-file, use -payload-file - for stdin.
+
+Flags:
+`
+
+// result is a printed response for a notification.
+type result struct {
+	Line        int    `json:"line,omitempty"`
+	DeviceToken string `json:"device_token,omitempty"`
+	ID          string `json:"apns_id,omitempty"`
+	Status      int    `json:"status,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+	Timestamp   int64  `json:"timestamp,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+func newResult(n *apns.Notification, res *apns.Response, err error) *result {
+	r := &result{DeviceToken: n.DeviceToken}
+	if res != nil {
+		r.ID = res.ID
+		r.Status = res.Status
+		r.Reason = res.Reason
+		r.Timestamp = res.Timestamp
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// ok reports whether the notification was accepted.
+func (r *result) ok() bool {
+	return r.Error == "" && r.Status == apns.Status200
+}
+
+func (r *result) print(w io.Writer, asJSON bool) {
+	if asJSON {
+		json.NewEncoder(w).Encode(r)
+		return
+	}
+
+	fmt.Fprintf(w, "device-token: %s\n", r.DeviceToken)
+	if r.Error != "" {
+		fmt.Fprintf(w, "error: %s\n", r.Error)
+		return
+	}
+	fmt.Fprintf(w, "apns-id: %s\n", r.ID)
+	fmt.Fprintf(w, "status: %d\n", r.Status)
+	if r.Reason != "" {
+		fmt.Fprintf(w, "reason: %s\n", r.Reason)
+	}
+	if r.Timestamp != 0 {
+		fmt.Fprintf(w, "timestamp: %s\n", time.Unix(0, r.Timestamp*int64(time.Millisecond)).UTC().Format(time.RFC3339))
+	}
+}
+
+func runSend(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("send", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, sendUsage)
+		fs.PrintDefaults()
+	}
+
+	var conn connFlags
+	conn.register(fs)
+	n := &apns.Notification{}
+	fs.StringVar(&n.Topic, "topic", "", "topic, the bundle ID with the suffix of the push type")
+	fs.StringVar(&n.PushType, "push-type", apns.PushTypeAlert, "push type: alert, background, voip, complication, fileprovider, mdm or liveactivity")
+	fs.IntVar(&n.Priority, "priority", 0, "priority: 10, 5 or 1, APNs uses 10 if omitted")
+	expiration := fs.String("expiration", "", "UNIX epoch in seconds, 0 to deliver only once or duration from now, for example, 1h")
+	fs.StringVar(&n.CollapseID, "collapse-id", "", "collapse ID")
+	fs.StringVar(&n.ID, "id", "", "apns-id UUID, APNs generates it if omitted")
+	payload := fs.String("payload", "", "JSON payload")
+	payloadFile := fs.String("payload-file", "", "`path` to JSON payload file, - for stdin")
+	asJSON := fs.Bool("json", false, "print responses as JSON lines")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(stderr, "apns send: device token is required")
+		fs.Usage()
+		return 2
+	}
+	if err := setExpiration(n, *expiration); err != nil {
+		fmt.Fprintf(stderr, "apns send: %v\n", err)
+		return 2
+	}
+
+	b, err := readPayload(*payload, *payloadFile, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "apns send: %v\n", err)
+		return 2
+	}
+	n.Payload = b
+
+	n.Host, err = conn.Host()
+	if err != nil {
+		fmt.Fprintf(stderr, "apns send: %v\n", err)
+		return 2
+	}
+	client, err := conn.Client()
+	if err != nil {
+		fmt.Fprintf(stderr, "apns send: %v\n", err)
+		return 1
+	}
+	client.Validate = true
+
+	code := 0
+	for i, token := range fs.Args() {
+		n := *n
+		n.DeviceToken = token
+		res, err := client.PushWithContext(context.Background(), &n)
+
+		r := newResult(&n, res, err)
+		if i > 0 && !*asJSON {
+			fmt.Fprintln(stdout)
+		}
+		r.print(stdout, *asJSON)
+		if !r.ok() {
+			code = 1
+		}
+	}
+	return code
+}
+
+// readPayload returns JSON payload from the flag value, the named file or stdin for -.
+func readPayload(payload, name string, stdin io.Reader) ([]byte, error) {
+	var b []byte
+	var err error
+	switch {
+	case payload != "" && name != "":
+		return nil, errors.New("-payload and -payload-file are mutually exclusive")
+	case payload != "":
+		b = []byte(payload)
+	case name == "-":
+		b, err = io.ReadAll(stdin)
+	case name != "":
+		b, err = os.ReadFile(name)
+	default:
+		return nil, errors.New("payload is required: use -payload or -payload-file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(b) {
+		return nil, errors.New("payload is not valid JSON")
+	}
+	return b, nil
+}
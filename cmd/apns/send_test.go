@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bergusman/apns-go"
+	"github.com/bergusman/apns-go/apnstest"
+)
+
+const (
+	keyFile     = "../../testdata/AuthKey_5MDQ4KLTY7.p8"
+	deviceToken = "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e"
+)
+
+func newTestServer(t *testing.T) *apnstest.Server {
+	key, err := apns.AuthKeyFromFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := apnstest.NewServer()
+	s.AddKey("5MDQ4KLTY7", "SUPERTEEM1", &key.PublicKey)
+	return s
+}
+
+func connArgs(s *apnstest.Server) []string {
+	return []string{"-key", keyFile, "-key-id", "5MDQ4KLTY7", "-team-id", "SUPERTEEM1", "-host", s.URL, "-insecure"}
+}
+
+func TestSend(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	args := append([]string{"send"}, connArgs(s)...)
+	args = append(args,
+		"-topic", "com.example.app",
+		"-push-type", "background",
+		"-priority", "5",
+		"-expiration", "1h",
+		"-collapse-id", "sync",
+		"-id", "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D",
+		"-payload", `{"aps":{"content-available":1}}`,
+		deviceToken,
+	)
+	var stdout, stderr bytes.Buffer
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("code: %v; stderr: %v", code, stderr.String())
+	}
+
+	want := "device-token: " + deviceToken + "\napns-id: EC1BF194-B3B2-424A-89A9-5A918A6E6B5D\nstatus: 200\n"
+	if stdout.String() != want {
+		t.Errorf("stdout: %q; want: %q", stdout.String(), want)
+	}
+
+	pushes := s.Pushes()
+	if len(pushes) != 1 {
+		t.Fatalf("pushes: %v; want: 1", len(pushes))
+	}
+	n := pushes[0].Notification
+	if n.Topic != "com.example.app" || n.PushType != apns.PushTypeBackground || n.Priority != apns.PriorityLow || n.CollapseID != "sync" {
+		t.Errorf("headers: %v %v %v %v", n.Topic, n.PushType, n.Priority, n.CollapseID)
+	}
+	if d := n.ExpiresIn(nil); d < 59*time.Minute || d > time.Hour {
+		t.Errorf("expires in: %v; want: 1h", d)
+	}
+	if string(n.Payload.([]byte)) != `{"aps":{"content-available":1}}` {
+		t.Errorf("payload: %s", n.Payload)
+	}
+}
+
+func TestSendJSON(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	s.Unregister(deviceToken, time.Unix(1629000000, 0))
+
+	name := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(name, []byte(`{"aps":{"alert":"Hello"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	other := strings.Repeat("a", 64)
+	args := append([]string{"send"}, connArgs(s)...)
+	args = append(args, "-topic", "com.example.app", "-payload-file", name, "-json", deviceToken, other)
+	var stdout, stderr bytes.Buffer
+	if code := run(args, nil, &stdout, &stderr); code != 1 {
+		t.Fatalf("code: %v; want: 1; stderr: %v", code, stderr.String())
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines: %q", lines)
+	}
+	var r result
+	if err := json.Unmarshal([]byte(lines[0]), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.DeviceToken != deviceToken || r.Status != apns.Status410 || r.Reason != apns.ReasonUnregistered || r.Timestamp != 1629000000000 {
+		t.Errorf("result: %+v", r)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.DeviceToken != other || r.Status != apns.Status200 || r.ID == "" {
+		t.Errorf("result: %+v", r)
+	}
+}
+
+func TestSendStdin(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	args := append([]string{"send"}, connArgs(s)...)
+	args = append(args, "-topic", "com.example.app", "-payload-file", "-", deviceToken)
+	var stdout, stderr bytes.Buffer
+	if code := run(args, strings.NewReader(`{"aps":{"alert":"Hi"}}`), &stdout, &stderr); code != 0 {
+		t.Fatalf("code: %v; stderr: %v", code, stderr.String())
+	}
+	if p := s.Pushes(); len(p) != 1 || string(p[0].Notification.Payload.([]byte)) != `{"aps":{"alert":"Hi"}}` {
+		t.Errorf("pushes: %v", p)
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+
+	tests := []struct {
+		args []string
+		code int
+		err  string
+	}{
+		{[]string{"-payload", "{}"}, 2, "device token is required"},
+		{[]string{deviceToken}, 2, "payload is required"},
+		{[]string{"-payload", "{", deviceToken}, 2, "not valid JSON"},
+		{[]string{"-payload", "{}", "-payload-file", "-", deviceToken}, 2, "mutually exclusive"},
+		{[]string{"-payload", "{}", "-expiration", "tomorrow", deviceToken}, 2, "invalid expiration"},
+		{[]string{"-payload", "{}", "-host", "", "-env", "staging", deviceToken}, 2, "unknown environment"},
+		{[]string{"-payload", "{}", "-key", "../../testdata/AuthKeyRSA.p8", deviceToken}, 1, apns.ErrAuthKeyNotECDSAP256.Error()},
+		{[]string{"-payload", "{}", "-priority", "7", deviceToken}, 1, apns.ReasonBadPriority},
+	}
+	for _, test := range tests {
+		args := append([]string{"send"}, connArgs(s)...)
+		args = append(args, test.args...)
+		var stdout, stderr bytes.Buffer
+		code := run(args, strings.NewReader(""), &stdout, &stderr)
+		if code != test.code {
+			t.Errorf("%v: code: %v; want: %v", test.args, code, test.code)
+		}
+		if !strings.Contains(stdout.String()+stderr.String(), test.err) {
+			t.Errorf("%v: output: %q; want: %q", test.args, stdout.String()+stderr.String(), test.err)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bergusman/apns-go"
+	"github.com/bergusman/apns-go/apnstest"
+)
+
+const serveUsage = `Usage:
+
+	apns serve [flags]
+
+Serve runs a fake APNs server on -addr: a TLS HTTP/2 server implementing
+the /3/device/<device_token> endpoint that verifies provider tokens
+signed with -key and answers like APNs does.
+Each received notification is logged with its headers and payload.
+The latest -max-pushes received notifications are listed in JSON
+on http://<-list-addr>/pushes, DELETE request to it forgets them.
+
+Without -tls-cert the server uses a self-signed certificate,
+so clients must skip certificate verification, for example, apns send -insecure.
+
+Flags:
+`
+
+func runServe(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, serveUsage)
+		fs.PrintDefaults()
+	}
+
+	keyName := fs.String("key", "", "`path` to .p8 authentication token signing key")
+	keyID := fs.String("key-id", "", "key ID of the signing key")
+	teamID := fs.String("team-id", "", "team ID of the developer account")
+	addr := fs.String("addr", "localhost:2197", "`address` of the fake APNs server")
+	listAddr := fs.String("list-addr", "localhost:8080", "`address` of the listing of received notifications, empty to disable")
+	maxPushes := fs.Int("max-pushes", 1000, "maximum number of kept notifications, the oldest ones are forgotten")
+	certFile := fs.String("tls-cert", "", "`path` to PEM encoded TLS certificate instead of self-signed one")
+	certKeyFile := fs.String("tls-key", "", "`path` to PEM encoded private key of -tls-cert")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+	if *keyName == "" || *keyID == "" || *teamID == "" {
+		fmt.Fprintln(stderr, "apns serve: -key, -key-id and -team-id are required")
+		return 2
+	}
+	if *maxPushes <= 0 {
+		fmt.Fprintln(stderr, "apns serve: -max-pushes must be positive")
+		return 2
+	}
+	if (*certFile == "") != (*certKeyFile == "") {
+		fmt.Fprintln(stderr, "apns serve: -tls-cert and -tls-key must be set together")
+		return 2
+	}
+
+	key, err := apns.AuthKeyFromFile(*keyName)
+	if err != nil {
+		fmt.Fprintf(stderr, "apns serve: %s: %v\n", *keyName, err)
+		return 1
+	}
+
+	s := apnstest.NewUnstartedServer()
+	s.AddKey(*keyID, *teamID, &key.PublicKey)
+	s.OnPush = (&pushLogger{w: stdout}).log
+	s.MaxPushes = *maxPushes
+	if *certFile != "" {
+		cert, err := tls.LoadX509KeyPair(*certFile, *certKeyFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "apns serve: %v\n", err)
+			return 1
+		}
+		s.TLS.Certificates = []tls.Certificate{cert}
+	}
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		fmt.Fprintf(stderr, "apns serve: %v\n", err)
+		return 1
+	}
+	s.Listener.Close()
+	s.Listener = ln
+	s.StartTLS()
+	defer s.Close()
+	fmt.Fprintf(stderr, "apns serve: APNs on %s\n", s.URL)
+
+	errs := make(chan error, 1)
+	if *listAddr != "" {
+		ls := &http.Server{Handler: pushList(s.Handler)}
+		defer ls.Close()
+		ln, err := net.Listen("tcp", *listAddr)
+		if err != nil {
+			fmt.Fprintf(stderr, "apns serve: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stderr, "apns serve: received notifications on http://%s/pushes\n", ln.Addr())
+		go func() {
+			errs <- ls.Serve(ln)
+		}()
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	select {
+	case <-ctx.Done():
+		return 0
+	case err := <-errs:
+		fmt.Fprintf(stderr, "apns serve: %v\n", err)
+		return 1
+	}
+}
+
+// pushLogger logs notification requests received by the fake APNs server.
+type pushLogger struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// log writes status line, headers and indented payload of p.
+func (l *pushLogger) log(p *apnstest.Push) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s /3/device/%s %d", p.Time.Format(time.RFC3339), p.Notification.DeviceToken, p.Response.Status)
+	if p.Response.Reason != "" {
+		fmt.Fprintf(&b, " %s", p.Response.Reason)
+	}
+	b.WriteByte('\n')
+	if p.KeyID != "" {
+		fmt.Fprintf(&b, "key-id: %s, team-id: %s\n", p.KeyID, p.TeamID)
+	}
+	header := pushHeader(p)
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %s\n", name, header[name])
+	}
+
+	payload := p.Notification.Payload.([]byte)
+	if err := json.Indent(&b, payload, "", "  "); err != nil {
+		b.Write(payload)
+	}
+	b.WriteString("\n\n")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(b.Bytes())
+}
+
+// pushHeader returns apns-* headers of p, the provider token is not exposed.
+func pushHeader(p *apnstest.Push) map[string]string {
+	header := make(map[string]string)
+	for name, values := range p.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "apns-") {
+			header[name] = strings.Join(values, ", ")
+		}
+	}
+	return header
+}
+
+// listedPush is a received notification in the listing.
+type listedPush struct {
+	Time        time.Time         `json:"time"`
+	DeviceToken string            `json:"device_token"`
+	KeyID       string            `json:"key_id,omitempty"`
+	TeamID      string            `json:"team_id,omitempty"`
+	Headers     map[string]string `json:"headers"`
+	Payload     json.RawMessage   `json:"payload"`
+	ID          string            `json:"apns_id"`
+	Status      int               `json:"status"`
+	Reason      string            `json:"reason,omitempty"`
+}
+
+// pushList returns handler listing notifications received by h.
+func pushList(h *apnstest.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/pushes", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodDelete:
+			h.Reset()
+			w.WriteHeader(http.StatusNoContent)
+			return
+		default:
+			w.Header().Set("Allow", "GET, HEAD, DELETE")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		pushes := h.Pushes()
+		list := make([]listedPush, len(pushes))
+		for i, p := range pushes {
+			list[i] = listedPush{
+				Time:        p.Time,
+				DeviceToken: p.Notification.DeviceToken,
+				KeyID:       p.KeyID,
+				TeamID:      p.TeamID,
+				Headers:     pushHeader(p),
+				Payload:     rawPayload(p.Notification.Payload.([]byte)),
+				ID:          p.Response.ID,
+				Status:      p.Response.Status,
+				Reason:      p.Response.Reason,
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(list)
+	})
+	return mux
+}
+
+// rawPayload returns payload as JSON, payload that is not valid JSON as string.
+func rawPayload(payload []byte) json.RawMessage {
+	if json.Valid(payload) {
+		return payload
+	}
+	b, err := json.Marshal(string(payload))
+	if err != nil {
+		return nil
+	}
+	return b
+}
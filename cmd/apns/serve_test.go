@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServeLog(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	var log bytes.Buffer
+	s.OnPush = (&pushLogger{w: &log}).log
+	unregistered := strings.Repeat("b", 64)
+	s.Unregister(unregistered, time.Unix(1629000000, 0))
+
+	args := append([]string{"send"}, connArgs(s)...)
+	args = append(args, "-topic", "com.example.app", "-collapse-id", "hi", "-payload", `{"aps":{"alert":"Hi"}}`, deviceToken, unregistered)
+	var stdout, stderr bytes.Buffer
+	run(args, nil, &stdout, &stderr)
+
+	for _, want := range []string{
+		"/3/device/" + deviceToken + " 200\n" +
+			"key-id: 5MDQ4KLTY7, team-id: SUPERTEEM1\n" +
+			"apns-collapse-id: hi\n" +
+			"apns-push-type: alert\n" +
+			"apns-topic: com.example.app\n" +
+			"{\n  \"aps\": {\n    \"alert\": \"Hi\"\n  }\n}\n\n",
+		"/3/device/" + unregistered + " 410 Unregistered\n",
+	} {
+		if !strings.Contains(log.String(), want) {
+			t.Errorf("log: %q; want: %q", log.String(), want)
+		}
+	}
+	if strings.Contains(log.String(), "authorization") {
+		t.Error("provider token must not be logged")
+	}
+}
+
+func TestServeList(t *testing.T) {
+	s := newTestServer(t)
+	defer s.Close()
+	ls := httptest.NewServer(pushList(s.Handler))
+	defer ls.Close()
+
+	args := append([]string{"send"}, connArgs(s)...)
+	args = append(args, "-topic", "com.example.app", "-payload", `{"aps":{"alert":"Hi"}}`, deviceToken)
+	var stdout, stderr bytes.Buffer
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("code: %v; stderr: %v", code, stderr.String())
+	}
+
+	res, err := http.Get(ls.URL + "/pushes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var list []listedPush
+	if err := json.NewDecoder(res.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("len(list): %v; want: 1", len(list))
+	}
+	p := list[0]
+	if p.DeviceToken != deviceToken || p.KeyID != "5MDQ4KLTY7" || p.Status != 200 {
+		t.Errorf("push: %+v", p)
+	}
+	if p.Headers["apns-topic"] != "com.example.app" {
+		t.Errorf("headers: %v", p.Headers)
+	}
+	var payload bytes.Buffer
+	if err := json.Compact(&payload, p.Payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.String() != `{"aps":{"alert":"Hi"}}` {
+		t.Errorf("payload: %s", p.Payload)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, ls.URL+"/pushes", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("StatusCode: %v; want: %v", res.StatusCode, http.StatusNoContent)
+	}
+	if len(s.Pushes()) != 0 {
+		t.Error("pushes must be forgotten")
+	}
+}
+
+func TestRawPayload(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    string
+	}{
+		{`{"aps":{}}`, `{"aps":{}}`},
+		{`{"aps":`, `"{\"aps\":"`},
+		{``, `""`},
+	}
+	for _, test := range tests {
+		if got := string(rawPayload([]byte(test.payload))); got != test.want {
+			t.Errorf("rawPayload(%q): %v; want: %v", test.payload, got, test.want)
+		}
+	}
+}
+
+func TestServeUsage(t *testing.T) {
+	tests := [][]string{
+		{"serve"},
+		{"serve", "-key", keyFile, "-key-id", "5MDQ4KLTY7"},
+		{"serve", "-key", keyFile, "-key-id", "5MDQ4KLTY7", "-team-id", "SUPERTEEM1", "-tls-cert", "cert.pem"},
+		{"serve", "extra"},
+		{"serve", "-key", keyFile, "-key-id", "5MDQ4KLTY7", "-team-id", "SUPERTEEM1", "-max-pushes", "0"},
+	}
+	for _, args := range tests {
+		var stdout, stderr bytes.Buffer
+		if code := run(args, nil, &stdout, &stderr); code != 2 {
+			t.Errorf("%v: code: %v; want: 2", args, code)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+const tokenUsage = `Usage:
+
+	apns token generate [flags]
+	apns token decode [flags] <bearer>
+
+Generate prints a provider token (bearer) signed by the .p8 key.
+Decode prints header, claims and age of the bearer, - reads it from stdin;
+with -key the signature is verified too.
+
+Run apns token <command> -h for flags of the command.
+`
+
+func runToken(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, tokenUsage)
+		return 2
+	}
+	switch args[0] {
+	case "generate":
+		return runTokenGenerate(args[1:], stdout, stderr)
+	case "decode":
+		return runTokenDecode(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, tokenUsage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "apns token: unknown command %q\n\n%s", args[0], tokenUsage)
+		return 2
+	}
+}
+
+func runTokenGenerate(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("token generate", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var conn connFlags
+	fs.StringVar(&conn.key, "key", "", "`path` to .p8 authentication token signing key")
+	fs.StringVar(&conn.keyID, "key-id", "", "key ID of the signing key")
+	fs.StringVar(&conn.teamID, "team-id", "", "team ID of the developer account")
+	issuedAt := fs.Int64("issued-at", 0, "UNIX epoch in seconds of the token, now if omitted")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+
+	token, err := conn.Token()
+	if err != nil {
+		fmt.Fprintf(stderr, "apns token generate: %v\n", err)
+		return 1
+	}
+	if *issuedAt == 0 {
+		*issuedAt = time.Now().Unix()
+	}
+	bearer, err := apns.GenerateBearer(token.Key, token.KeyID, token.TeamID, *issuedAt)
+	if err != nil {
+		fmt.Fprintf(stderr, "apns token generate: %v\n", err)
+		return 1
+	}
+	fmt.Fprintln(stdout, bearer)
+	return 0
+}
+
+// decodedBearer is a printed decoded bearer.
+type decodedBearer struct {
+	Algorithm    string `json:"alg"`
+	KeyID        string `json:"kid"`
+	TeamID       string `json:"iss"`
+	IssuedAt     int64  `json:"iat"`
+	Age          string `json:"age"`
+	NeedsRefresh bool   `json:"needs_refresh"`
+	Expired      bool   `json:"expired"`
+	Signature    string `json:"signature"`
+}
+
+func runTokenDecode(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("token decode", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	keyFile := fs.String("key", "", "`path` to .p8 key to verify the signature")
+	asJSON := fs.Bool("json", false, "print as JSON")
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "apns token decode: bearer is required")
+		return 2
+	}
+
+	s := fs.Arg(0)
+	if s == "-" {
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			fmt.Fprintf(stderr, "apns token decode: %v\n", err)
+			return 1
+		}
+		s = string(b)
+	}
+	b, err := apns.ParseBearer(strings.TrimSpace(s))
+	if err != nil {
+		fmt.Fprintf(stderr, "apns token decode: %v\n", err)
+		return 1
+	}
+
+	now := time.Now()
+	d := &decodedBearer{
+		Algorithm:    b.Algorithm,
+		KeyID:        b.KeyID,
+		TeamID:       b.TeamID,
+		IssuedAt:     b.IssuedAt,
+		Age:          b.Age(now).Truncate(time.Second).String(),
+		NeedsRefresh: b.NeedsRefresh(now),
+		Expired:      b.Expired(now),
+		Signature:    "not verified",
+	}
+
+	code := 0
+	if *keyFile != "" {
+		key, err := apns.AuthKeyFromFile(*keyFile)
+		if err != nil {
+			fmt.Fprintf(stderr, "apns token decode: %s: %v\n", *keyFile, err)
+			return 1
+		}
+		if err := b.Verify(&key.PublicKey); err != nil {
+			d.Signature = "invalid: " + err.Error()
+			code = 1
+		} else {
+			d.Signature = "valid"
+		}
+	}
+
+	if *asJSON {
+		json.NewEncoder(stdout).Encode(d)
+		return code
+	}
+	fmt.Fprintf(stdout, "alg: %s\n", d.Algorithm)
+	fmt.Fprintf(stdout, "kid: %s\n", d.KeyID)
+	fmt.Fprintf(stdout, "iss: %s\n", d.TeamID)
+	fmt.Fprintf(stdout, "iat: %d (%s)\n", d.IssuedAt, b.Time().UTC().Format(time.RFC3339))
+	fmt.Fprintf(stdout, "age: %s\n", d.Age)
+	switch {
+	case d.Expired:
+		fmt.Fprintln(stdout, "status: expired, APNs rejects it with ExpiredProviderToken")
+	case d.NeedsRefresh:
+		fmt.Fprintln(stdout, "status: needs refresh")
+	default:
+		fmt.Fprintln(stdout, "status: fresh")
+	}
+	fmt.Fprintf(stdout, "signature: %s\n", d.Signature)
+	return code
+}
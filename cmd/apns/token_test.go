@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bergusman/apns-go"
+)
+
+func TestTokenGenerate(t *testing.T) {
+	issuedAt := time.Now().Unix()
+	args := []string{"token", "generate", "-key", keyFile, "-key-id", "5MDQ4KLTY7", "-team-id", "SUPERTEEM1", "-issued-at", strconv.FormatInt(issuedAt, 10)}
+	var stdout, stderr bytes.Buffer
+	if code := run(args, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("code: %v; stderr: %v", code, stderr.String())
+	}
+
+	b, err := apns.ParseBearer(strings.TrimSpace(stdout.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.KeyID != "5MDQ4KLTY7" || b.TeamID != "SUPERTEEM1" || b.IssuedAt != issuedAt {
+		t.Errorf("bearer: %+v", b)
+	}
+	key, err := apns.AuthKeyFromFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Verify(&key.PublicKey); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTokenDecode(t *testing.T) {
+	key, err := apns.AuthKeyFromFile(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issuedAt := time.Now().Add(-50 * time.Minute).Unix()
+	bearer, err := apns.GenerateBearer(key, "5MDQ4KLTY7", "SUPERTEEM1", issuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"token", "decode", "-key", keyFile, bearer}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("code: %v; stderr: %v", code, stderr.String())
+	}
+	for _, want := range []string{"alg: ES256\n", "kid: 5MDQ4KLTY7\n", "iss: SUPERTEEM1\n", "age: 50m", "status: needs refresh\n", "signature: valid\n"} {
+		if !strings.Contains(stdout.String(), want) {
+			t.Errorf("stdout: %q; want: %q", stdout.String(), want)
+		}
+	}
+
+	// Expired bearer from stdin with authorization header prefix.
+	bearer, err = apns.GenerateBearer(key, "5MDQ4KLTY7", "SUPERTEEM1", time.Now().Add(-2*time.Hour).Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout.Reset()
+	if code := run([]string{"token", "decode", "-json", "-"}, strings.NewReader("bearer "+bearer+"\n"), &stdout, &stderr); code != 0 {
+		t.Fatalf("code: %v; stderr: %v", code, stderr.String())
+	}
+	var d decodedBearer
+	if err := json.Unmarshal(stdout.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.Expired || !d.NeedsRefresh || d.Signature != "not verified" {
+		t.Errorf("decoded: %+v", d)
+	}
+}
+
+func TestTokenDecodeErrors(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"token", "decode", "xxx"}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("code: %v; want: 1", code)
+	}
+	if !strings.Contains(stderr.String(), apns.ErrJWTBadBearer.Error()) {
+		t.Errorf("stderr: %v", stderr.String())
+	}
+
+	bearer := "eyJhbGciOiJFUzI1NiIsImtpZCI6IjVNRFE0S0xUWTcifQ.eyJpc3MiOiJTVVBFUlRFRU0xIiwiaWF0IjoxNjI5MDAwMDAwfQ.c2ln"
+	stdout.Reset()
+	if code := run([]string{"token", "decode", "-key", keyFile, bearer}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("code: %v; want: 1", code)
+	}
+	if !strings.Contains(stdout.String(), "signature: invalid") {
+		t.Errorf("stdout: %v", stdout.String())
+	}
+
+	stderr.Reset()
+	if code := run([]string{"token", "decode", "-key", "../../testdata/AuthKeyECDSAP224.p8", bearer}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("code: %v; want: 1", code)
+	}
+	if !strings.Contains(stderr.String(), apns.ErrAuthKeyNotECDSAP256.Error()) {
+		t.Errorf("stderr: %v", stderr.String())
+	}
+}
@@ -0,0 +1,225 @@
+package apns
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// InvalidDeviceToken is a device token APNs reported as no longer valid for the topic.
+// Stop sending notifications to it, unless your app registers it again.
+type InvalidDeviceToken struct {
+	DeviceToken string `json:"device_token"`
+	Topic       string `json:"topic,omitempty"`
+
+	// ReasonUnregistered or ReasonBadDeviceToken.
+	Reason string `json:"reason"`
+
+	// The time at which APNs confirmed the device token was no longer valid for the topic.
+	// Zero for ReasonBadDeviceToken.
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler, zero Timestamp is omitted.
+func (t InvalidDeviceToken) MarshalJSON() ([]byte, error) {
+	type token InvalidDeviceToken
+	v := struct {
+		token
+		Timestamp *time.Time `json:"timestamp,omitempty"`
+	}{token: token(t)}
+	if !t.Timestamp.IsZero() {
+		v.Timestamp = &t.Timestamp
+	}
+	return json.Marshal(v)
+}
+
+// DeviceTokenStore is invoked by Client when APNs reports device token as invalid:
+// Status 410 Unregistered or Status 400 BadDeviceToken.
+// Implement it to delete device tokens from your database.
+type DeviceTokenStore interface {
+	Invalidate(t InvalidDeviceToken) error
+}
+
+// invalidDeviceToken returns invalid device token reported by res for n.
+func invalidDeviceToken(n *Notification, res *Response) (InvalidDeviceToken, bool) {
+	if res == nil || n.DeviceToken == "" || n.ChannelID != "" {
+		return InvalidDeviceToken{}, false
+	}
+	switch {
+	case res.Status == Status410 && res.Reason == ReasonUnregistered:
+	case res.Status == Status400 && res.Reason == ReasonBadDeviceToken:
+	default:
+		return InvalidDeviceToken{}, false
+	}
+	return InvalidDeviceToken{
+		DeviceToken: n.DeviceToken,
+		Topic:       n.Topic,
+		Reason:      res.Reason,
+		Timestamp:   res.Time(),
+	}, true
+}
+
+// MemoryDeviceTokenStore is DeviceTokenStore keeping invalid device tokens in memory.
+type MemoryDeviceTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]InvalidDeviceToken
+}
+
+// NewMemoryDeviceTokenStore returns empty MemoryDeviceTokenStore.
+func NewMemoryDeviceTokenStore() *MemoryDeviceTokenStore {
+	return &MemoryDeviceTokenStore{
+		tokens: make(map[string]InvalidDeviceToken),
+	}
+}
+
+// Invalidate implements DeviceTokenStore.
+func (s *MemoryDeviceTokenStore) Invalidate(t InvalidDeviceToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[t.DeviceToken] = t
+	return nil
+}
+
+// Invalid returns invalid device token info and true if deviceToken was invalidated.
+func (s *MemoryDeviceTokenStore) Invalid(deviceToken string) (InvalidDeviceToken, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tokens[deviceToken]
+	return t, ok
+}
+
+// Remove forgets deviceToken, for example, when the app registers it again.
+// It never returns error, the error is for the same signature as FileDeviceTokenStore.Remove.
+func (s *MemoryDeviceTokenStore) Remove(deviceToken string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.tokens, deviceToken)
+	return nil
+}
+
+// InvalidTokens returns all invalid device tokens sorted by device token.
+func (s *MemoryDeviceTokenStore) InvalidTokens() []InvalidDeviceToken {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	tokens := make([]InvalidDeviceToken, 0, len(s.tokens))
+	for _, t := range s.tokens {
+		tokens = append(tokens, t)
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		return tokens[i].DeviceToken < tokens[j].DeviceToken
+	})
+	return tokens
+}
+
+// FileDeviceTokenStore is DeviceTokenStore appending invalid device tokens
+// to a file in JSON Lines format, one InvalidDeviceToken per line.
+// Removed device tokens are appended as {"device_token":"...","removed":true} lines.
+// It keeps invalid device tokens in memory too, see MemoryDeviceTokenStore.
+type FileDeviceTokenStore struct {
+	mem *MemoryDeviceTokenStore
+
+	mu   sync.Mutex
+	name string
+}
+
+// OpenFileDeviceTokenStore loads invalid device tokens from the named file
+// and returns store that appends new ones to it.
+// The file is created on first Invalidate if it does not exist.
+func OpenFileDeviceTokenStore(name string) (*FileDeviceTokenStore, error) {
+	s := &FileDeviceTokenStore{
+		mem:  NewMemoryDeviceTokenStore(),
+		name: name,
+	}
+
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var t struct {
+			InvalidDeviceToken
+			Removed bool `json:"removed"`
+		}
+		if err := json.Unmarshal(sc.Bytes(), &t); err != nil {
+			return nil, err
+		}
+		if t.Removed {
+			s.mem.Remove(t.DeviceToken)
+		} else {
+			s.mem.Invalidate(t.InvalidDeviceToken)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Invalidate implements DeviceTokenStore.
+func (s *FileDeviceTokenStore) Invalidate(t InvalidDeviceToken) error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(b); err != nil {
+		return err
+	}
+	return s.mem.Invalidate(t)
+}
+
+// Remove forgets deviceToken, for example, when the app registers it again,
+// and records the removal in the file, so the store opened again doesn't restore it.
+func (s *FileDeviceTokenStore) Remove(deviceToken string) error {
+	b, err := json.Marshal(struct {
+		DeviceToken string `json:"device_token"`
+		Removed     bool   `json:"removed"`
+	}{deviceToken, true})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.append(b); err != nil {
+		return err
+	}
+	return s.mem.Remove(deviceToken)
+}
+
+// Invalid returns invalid device token info and true if deviceToken was invalidated.
+func (s *FileDeviceTokenStore) Invalid(deviceToken string) (InvalidDeviceToken, bool) {
+	return s.mem.Invalid(deviceToken)
+}
+
+// InvalidTokens returns all invalid device tokens sorted by device token.
+func (s *FileDeviceTokenStore) InvalidTokens() []InvalidDeviceToken {
+	return s.mem.InvalidTokens()
+}
+
+// append writes line b to the file.
+func (s *FileDeviceTokenStore) append(b []byte) error {
+	f, err := os.OpenFile(s.name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(b, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
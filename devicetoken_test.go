@@ -0,0 +1,245 @@
+package apns
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemoryDeviceTokenStore(t *testing.T) {
+	s := NewMemoryDeviceTokenStore()
+	at := time.Date(2021, 8, 15, 4, 0, 0, 0, time.UTC)
+	s.Invalidate(InvalidDeviceToken{DeviceToken: "bb", Reason: ReasonBadDeviceToken})
+	s.Invalidate(InvalidDeviceToken{DeviceToken: "aa", Topic: "com.example.app", Reason: ReasonUnregistered, Timestamp: at})
+
+	tokens := s.InvalidTokens()
+	if len(tokens) != 2 || tokens[0].DeviceToken != "aa" || tokens[1].DeviceToken != "bb" {
+		t.Errorf("got: %v", tokens)
+	}
+
+	it, ok := s.Invalid("aa")
+	if !ok {
+		t.Fatal("aa must be invalid")
+	}
+	if it.Reason != ReasonUnregistered || !it.Timestamp.Equal(at) {
+		t.Errorf("got: %v", it)
+	}
+
+	s.Remove("aa")
+	if _, ok := s.Invalid("aa"); ok {
+		t.Error("aa must be removed")
+	}
+}
+
+func TestFileDeviceTokenStore(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "invalid.jsonl")
+
+	s, err := OpenFileDeviceTokenStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2021, 8, 15, 4, 0, 0, 0, time.UTC)
+	err = s.Invalidate(InvalidDeviceToken{DeviceToken: "aa", Topic: "com.example.app", Reason: ReasonUnregistered, Timestamp: at})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Invalidate(InvalidDeviceToken{DeviceToken: "bb", Topic: "com.example.app", Reason: ReasonBadDeviceToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenFileDeviceTokenStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokens := s.InvalidTokens()
+	if len(tokens) != 2 {
+		t.Fatalf("len(tokens): %v; want: 2", len(tokens))
+	}
+	if tokens[0].DeviceToken != "aa" || !tokens[0].Timestamp.Equal(at) || tokens[0].Topic != "com.example.app" {
+		t.Errorf("got: %v", tokens[0])
+	}
+	if tokens[1].DeviceToken != "bb" || tokens[1].Reason != ReasonBadDeviceToken {
+		t.Errorf("got: %v", tokens[1])
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), `"timestamp"`) != 1 {
+		t.Errorf("zero timestamp must be omitted: %s", b)
+	}
+
+	_, err = OpenFileDeviceTokenStore("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err == nil {
+		t.Error("err must be not nil")
+	}
+}
+
+func TestFileDeviceTokenStoreRemove(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "invalid.jsonl")
+
+	s, err := OpenFileDeviceTokenStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"aa", "bb"} {
+		if err := s.Invalidate(InvalidDeviceToken{DeviceToken: token, Reason: ReasonBadDeviceToken}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Remove("aa"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Invalid("aa"); ok {
+		t.Error("aa must be removed")
+	}
+
+	s, err = OpenFileDeviceTokenStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Invalid("aa"); ok {
+		t.Error("aa must be removed after reopen")
+	}
+	if _, ok := s.Invalid("bb"); !ok {
+		t.Error("bb must be invalid after reopen")
+	}
+
+	// Invalidated again after removal.
+	if err := s.Invalidate(InvalidDeviceToken{DeviceToken: "aa", Reason: ReasonBadDeviceToken}); err != nil {
+		t.Fatal(err)
+	}
+	s, err = OpenFileDeviceTokenStore(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Invalid("aa"); !ok {
+		t.Error("aa must be invalid after reopen")
+	}
+}
+
+func TestDeviceTokenStoreRemove(t *testing.T) {
+	file, err := OpenFileDeviceTokenStore(filepath.Join(t.TempDir(), "invalid.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stores := []interface {
+		DeviceTokenStore
+		Invalid(deviceToken string) (InvalidDeviceToken, bool)
+		Remove(deviceToken string) error
+	}{NewMemoryDeviceTokenStore(), file}
+
+	for _, s := range stores {
+		if err := s.Invalidate(InvalidDeviceToken{DeviceToken: "aa", Reason: ReasonBadDeviceToken}); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Remove("aa"); err != nil {
+			t.Errorf("%T: %v", s, err)
+		}
+		if _, ok := s.Invalid("aa"); ok {
+			t.Errorf("%T: aa must be removed", s)
+		}
+	}
+}
+
+func TestInvalidDeviceTokenMarshalJSON(t *testing.T) {
+	tests := []struct {
+		token InvalidDeviceToken
+		want  string
+	}{
+		{
+			InvalidDeviceToken{DeviceToken: "aa", Reason: ReasonBadDeviceToken},
+			`{"device_token":"aa","reason":"BadDeviceToken"}`,
+		},
+		{
+			InvalidDeviceToken{DeviceToken: "aa", Topic: "com.example.app", Reason: ReasonUnregistered, Timestamp: time.Unix(1629000000, 0).UTC()},
+			`{"device_token":"aa","topic":"com.example.app","reason":"Unregistered","timestamp":"2021-08-15T04:00:00Z"}`,
+		},
+	}
+	for _, test := range tests {
+		b, err := json.Marshal(test.token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != test.want {
+			t.Errorf("got: %s; want: %s", b, test.want)
+		}
+	}
+}
+
+type failingDeviceTokenStore struct{}
+
+func (failingDeviceTokenStore) Invalidate(t InvalidDeviceToken) error {
+	return errors.New("store failed")
+}
+
+func TestClientDeviceTokenStore(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/3/device/aa":
+			w.WriteHeader(Status410)
+			w.Write([]byte(`{"reason":"Unregistered","timestamp":1629000000000}`))
+		case "/3/device/bb":
+			w.WriteHeader(Status400)
+			w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+		case "/3/device/cc":
+			w.WriteHeader(Status400)
+			w.Write([]byte(`{"reason":"BadTopic"}`))
+		}
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	store := NewMemoryDeviceTokenStore()
+	client := NewClient(NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"), ts.Client())
+	client.DeviceTokenStore = store
+
+	for _, deviceToken := range []string{"aa", "bb", "cc", "dd"} {
+		_, err := client.Push(&Notification{
+			DeviceToken: deviceToken,
+			Host:        ts.URL,
+			Topic:       "com.example.app",
+			Payload:     `{}`,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tokens := store.InvalidTokens()
+	if len(tokens) != 2 {
+		t.Fatalf("got: %v", tokens)
+	}
+	want := InvalidDeviceToken{
+		DeviceToken: "aa",
+		Topic:       "com.example.app",
+		Reason:      ReasonUnregistered,
+		Timestamp:   time.Unix(1629000000, 0),
+	}
+	if tokens[0] != want {
+		t.Errorf("got: %v; want: %v", tokens[0], want)
+	}
+	if tokens[1].DeviceToken != "bb" || tokens[1].Reason != ReasonBadDeviceToken || !tokens[1].Timestamp.IsZero() {
+		t.Errorf("got: %v", tokens[1])
+	}
+
+	client.DeviceTokenStore = failingDeviceTokenStore{}
+	res, err := client.Push(&Notification{DeviceToken: "aa", Host: ts.URL, Payload: `{}`})
+	if err == nil || res == nil {
+		t.Errorf("got: %v, %v; want response and store error", res, err)
+	}
+}
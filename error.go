@@ -0,0 +1,129 @@
+package apns
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// Error is a failure reported by APNs in a response with non-200 status.
+// Use errors.Is with ErrReason* values to check the reason
+// and errors.As to get the response details.
+type Error struct {
+	// The apns-id of the failed notification.
+	ID string
+
+	// The HTTP status code.
+	Status int
+
+	// The error code indicating the reason for the failure.
+	Reason string
+
+	// The time at which APNs confirmed the device token was no longer valid for the topic.
+	// Zero unless Status is 410.
+	Timestamp time.Time
+}
+
+func (e *Error) Error() string {
+	return "apns: " + strconv.Itoa(e.Status) + " " + e.Reason
+}
+
+// Is reports whether target is *Error with the same reason,
+// or with the same status if target reason is empty.
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	if !ok {
+		return false
+	}
+	if t.Reason == "" {
+		return t.Status == e.Status
+	}
+	return t.Reason == e.Reason
+}
+
+// Err returns *Error for response with non-200 status or nil.
+func (r *Response) Err() error {
+	if r.Status == Status200 {
+		return nil
+	}
+	return &Error{
+		ID:        r.ID,
+		Status:    r.Status,
+		Reason:    r.Reason,
+		Timestamp: r.Time(),
+	}
+}
+
+// Time returns Timestamp as time.Time or zero time if Timestamp is omitted.
+func (r *Response) Time() time.Time {
+	if r.Timestamp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, r.Timestamp*int64(time.Millisecond))
+}
+
+// Error values for the reasons of APNs responses.
+var (
+	ErrReasonBadCollapseId               = &Error{Status: Status400, Reason: ReasonBadCollapseId}
+	ErrReasonInvalidCollapseId           = &Error{Status: Status400, Reason: ReasonInvalidCollapseId}
+	ErrReasonBadDeviceToken              = &Error{Status: Status400, Reason: ReasonBadDeviceToken}
+	ErrReasonBadExpirationDate           = &Error{Status: Status400, Reason: ReasonBadExpirationDate}
+	ErrReasonBadMessageId                = &Error{Status: Status400, Reason: ReasonBadMessageId}
+	ErrReasonBadPriority                 = &Error{Status: Status400, Reason: ReasonBadPriority}
+	ErrReasonBadTopic                    = &Error{Status: Status400, Reason: ReasonBadTopic}
+	ErrReasonDeviceTokenNotForTopic      = &Error{Status: Status400, Reason: ReasonDeviceTokenNotForTopic}
+	ErrReasonDuplicateHeaders            = &Error{Status: Status400, Reason: ReasonDuplicateHeaders}
+	ErrReasonIdleTimeout                 = &Error{Status: Status400, Reason: ReasonIdleTimeout}
+	ErrReasonInvalidPushType             = &Error{Status: Status400, Reason: ReasonInvalidPushType}
+	ErrReasonMissingDeviceToken          = &Error{Status: Status400, Reason: ReasonMissingDeviceToken}
+	ErrReasonMissingTopic                = &Error{Status: Status400, Reason: ReasonMissingTopic}
+	ErrReasonPayloadEmpty                = &Error{Status: Status400, Reason: ReasonPayloadEmpty}
+	ErrReasonTopicDisallowed             = &Error{Status: Status400, Reason: ReasonTopicDisallowed}
+	ErrReasonBadCertificate              = &Error{Status: Status403, Reason: ReasonBadCertificate}
+	ErrReasonBadCertificateEnvironment   = &Error{Status: Status403, Reason: ReasonBadCertificateEnvironment}
+	ErrReasonExpiredProviderToken        = &Error{Status: Status403, Reason: ReasonExpiredProviderToken}
+	ErrReasonForbidden                   = &Error{Status: Status403, Reason: ReasonForbidden}
+	ErrReasonInvalidProviderToken        = &Error{Status: Status403, Reason: ReasonInvalidProviderToken}
+	ErrReasonMissingProviderToken        = &Error{Status: Status403, Reason: ReasonMissingProviderToken}
+	ErrReasonBadPath                     = &Error{Status: Status404, Reason: ReasonBadPath}
+	ErrReasonMethodNotAllowed            = &Error{Status: Status405, Reason: ReasonMethodNotAllowed}
+	ErrReasonUnregistered                = &Error{Status: Status410, Reason: ReasonUnregistered}
+	ErrReasonPayloadTooLarge             = &Error{Status: Status413, Reason: ReasonPayloadTooLarge}
+	ErrReasonTooManyProviderTokenUpdates = &Error{Status: Status429, Reason: ReasonTooManyProviderTokenUpdates}
+	ErrReasonTooManyRequests             = &Error{Status: Status429, Reason: ReasonTooManyRequests}
+	ErrReasonInternalServerError         = &Error{Status: Status500, Reason: ReasonInternalServerError}
+	ErrReasonServiceUnavailable          = &Error{Status: Status503, Reason: ReasonServiceUnavailable}
+	ErrReasonShutdown                    = &Error{Status: Status503, Reason: ReasonShutdown}
+)
+
+// IsUnregistered reports whether err is Status 410 Unregistered,
+// the device token is inactive for the topic.
+func IsUnregistered(err error) bool {
+	return errors.Is(err, ErrReasonUnregistered)
+}
+
+// IsRetryable reports whether the notification failed with err
+// is safe to send again, see Retryable.
+func IsRetryable(err error) bool {
+	var e *Error
+	if errors.As(err, &e) {
+		return Retryable(&Response{Status: e.Status, Reason: e.Reason}, nil)
+	}
+	return err != nil && Retryable(nil, err)
+}
+
+// IsAuthError reports whether err is caused by the provider certificate or token:
+// any Status 403 error or Status 429 TooManyProviderTokenUpdates.
+func IsAuthError(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.Status == Status403 || e.Reason == ReasonTooManyProviderTokenUpdates
+}
+
+// IsPayloadError reports whether err is caused by the notification payload:
+// Status 400 PayloadEmpty or Status 413 PayloadTooLarge.
+func IsPayloadError(err error) bool {
+	return errors.Is(err, ErrReasonPayloadEmpty) || errors.Is(err, ErrReasonPayloadTooLarge)
+}
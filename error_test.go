@@ -0,0 +1,92 @@
+package apns
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestResponseErr(t *testing.T) {
+	res := &Response{Status: Status200}
+	if res.Err() != nil {
+		t.Errorf("got: %v; want nil", res.Err())
+	}
+
+	res = &Response{
+		ID:        "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D",
+		Status:    Status410,
+		Reason:    ReasonUnregistered,
+		Timestamp: 1629000000000,
+	}
+	err := res.Err()
+	if err == nil {
+		t.Fatal("err must be not nil")
+	}
+	if err.Error() != "apns: 410 Unregistered" {
+		t.Errorf("got: %q; want: %q", err.Error(), "apns: 410 Unregistered")
+	}
+	if !errors.Is(err, ErrReasonUnregistered) {
+		t.Error("err must be ErrReasonUnregistered")
+	}
+	if errors.Is(err, ErrReasonBadDeviceToken) {
+		t.Error("err must not be ErrReasonBadDeviceToken")
+	}
+	if !errors.Is(fmt.Errorf("push: %w", err), &Error{Status: Status410}) {
+		t.Error("wrapped err must match by status")
+	}
+
+	var e *Error
+	if !errors.As(err, &e) {
+		t.Fatal("err must be *Error")
+	}
+	if e.ID != res.ID {
+		t.Errorf("e.ID: %v; want: %v", e.ID, res.ID)
+	}
+	want := time.Date(2021, 8, 15, 4, 0, 0, 0, time.UTC)
+	if !e.Timestamp.Equal(want) {
+		t.Errorf("e.Timestamp: %v; want: %v", e.Timestamp, want)
+	}
+}
+
+func TestResponseTime(t *testing.T) {
+	res := &Response{}
+	if !res.Time().IsZero() {
+		t.Errorf("got: %v; want zero time", res.Time())
+	}
+}
+
+func TestErrorPredicates(t *testing.T) {
+	tests := []struct {
+		err                           error
+		unregistered, retryable, auth bool
+		payload                       bool
+	}{
+		{ErrReasonUnregistered, true, false, false, false},
+		{ErrReasonShutdown, false, true, false, false},
+		{ErrReasonTooManyRequests, false, true, false, false},
+		{ErrReasonInvalidProviderToken, false, false, true, false},
+		{ErrReasonTooManyProviderTokenUpdates, false, false, true, false},
+		{ErrReasonPayloadTooLarge, false, false, false, true},
+		{ErrReasonPayloadEmpty, false, false, false, true},
+		{io.EOF, false, false, false, false},
+		{syscall.ECONNRESET, false, true, false, false},
+		{nil, false, false, false, false},
+	}
+	for i, tt := range tests {
+		if got := IsUnregistered(tt.err); got != tt.unregistered {
+			t.Errorf("%v: IsUnregistered: %v; want: %v", i, got, tt.unregistered)
+		}
+		if got := IsRetryable(tt.err); got != tt.retryable {
+			t.Errorf("%v: IsRetryable: %v; want: %v", i, got, tt.retryable)
+		}
+		if got := IsAuthError(tt.err); got != tt.auth {
+			t.Errorf("%v: IsAuthError: %v; want: %v", i, got, tt.auth)
+		}
+		if got := IsPayloadError(tt.err); got != tt.payload {
+			t.Errorf("%v: IsPayloadError: %v; want: %v", i, got, tt.payload)
+		}
+	}
+}
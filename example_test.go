@@ -1,7 +1,9 @@
 package apns_test
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
@@ -49,6 +51,61 @@ func Example() {
 	}
 }
 
+func ExampleClient_PushAll() {
+	key, err := apns.AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token := apns.NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	client := apns.NewClient(token, nil)
+
+	deviceTokens := []string{
+		"7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e",
+		"1bc5b7c15ad4bc8a4a5b6e8d4d8a37e7f4c79d8c4a2b1fd6a3ea0f33b7c4a9e1",
+	}
+
+	ns := make(chan *apns.Notification)
+	go func() {
+		defer close(ns)
+		for _, deviceToken := range deviceTokens {
+			ns <- &apns.Notification{
+				DeviceToken: deviceToken,
+				Topic:       "com.example.app",
+				Host:        apns.HostDevelopment,
+				Payload: apns.BuildPayload(&apns.APS{
+					Alert: "Hello",
+				}, nil),
+			}
+		}
+	}()
+
+	for r := range client.PushAll(context.Background(), ns, 8) {
+		if r.Err != nil {
+			fmt.Println(r.Notification.DeviceToken, "failed:", r.Err)
+		} else {
+			fmt.Println(r.Notification.DeviceToken, r.Response.Status, r.Response.Reason)
+		}
+	}
+}
+
+func ExampleResponse_Err() {
+	res := &apns.Response{
+		Status:    apns.Status410,
+		Reason:    apns.ReasonUnregistered,
+		Timestamp: 1629000000000,
+	}
+
+	err := res.Err()
+	var e *apns.Error
+	if errors.As(err, &e) && apns.IsUnregistered(err) {
+		fmt.Println(e.Reason, e.Timestamp.UTC())
+	}
+
+	// Output:
+	// Unregistered 2021-08-15 04:00:00 +0000 UTC
+}
+
 func ExampleNotification() {
 	key, err := apns.AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
 	if err != nil {
@@ -152,6 +209,58 @@ func ExampleBuildPayload() {
 	// {"aps":{"alert":"Hello"},"type":"hello"}
 }
 
+func ExampleAPS_liveActivity() {
+	type ContentState struct {
+		Score  int    `json:"score"`
+		Status string `json:"status"`
+	}
+
+	n := &apns.Notification{
+		DeviceToken: "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e",
+		Topic:       "com.example.app.push-type.liveactivity",
+		PushType:    apns.PushTypeLiveActivity,
+		Priority:    apns.PriorityHigh,
+		Payload: apns.BuildPayload(&apns.APS{
+			Timestamp: 1629000060,
+			Event:     apns.LiveActivityEventUpdate,
+			ContentState: ContentState{
+				Score:  1,
+				Status: "playing",
+			},
+		}, nil),
+	}
+
+	b, err := json.Marshal(n)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(string(b))
+
+	// Output:
+	// {"aps":{"timestamp":1629000060,"event":"update","content-state":{"score":1,"status":"playing"}}}
+}
+
+func ExampleBuilder() {
+	n, err := apns.NewBuilder(apns.PushTypeBackground, "com.example.app").
+		DeviceToken("7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e").
+		Custom("sync", true).
+		Build()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	b, err := json.Marshal(n)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(n.Topic, n.PushType, n.Priority)
+	fmt.Println(string(b))
+
+	// Output:
+	// com.example.app background 5
+	// {"aps":{"content-available":1},"sync":true}
+}
+
 func ExampleGenerateBearer() {
 	key, err := apns.AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
 	if err != nil {
@@ -0,0 +1,197 @@
+package apns
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+var (
+	// Payload is not map built by BuildPayload
+	// or it exceeds maximum size even with truncated strings.
+	ErrPayloadCannotFit = errors.New("payload: cannot fit maximum size")
+)
+
+// Ellipsis appended to strings truncated by Notification.FitPayload.
+const Ellipsis = "…"
+
+// truncatable is a string of the payload FitPayload can truncate.
+type truncatable struct {
+	value string
+	set   func(string)
+}
+
+// FitPayload truncates strings of the payload until its JSON fits
+// the size MaxPayloadSize returns, so APNs doesn't reject it with PayloadTooLarge.
+// Alert body is truncated first, then alert subtitle,
+// then string values of customKeys in the given order.
+// Strings are cut on grapheme cluster boundaries and end with Ellipsis,
+// string that can't fit even as Ellipsis is removed.
+//
+// Payload must be map built by BuildPayload, it is replaced with a truncated copy,
+// so values shared with the original payload are not modified.
+// Returns ErrPayloadCannotFit and keeps Payload if it can't fit.
+func (n *Notification) FitPayload(customKeys ...string) error {
+	ok, err := n.payloadFits()
+	if err != nil || ok {
+		return err
+	}
+
+	original, ok := n.Payload.(map[string]interface{})
+	if !ok {
+		return ErrPayloadCannotFit
+	}
+
+	payload := make(map[string]interface{}, len(original))
+	for k, v := range original {
+		payload[k] = v
+	}
+	n.Payload = payload
+
+	var fields []truncatable
+	if aps, ok := payload["aps"].(*APS); ok && aps != nil {
+		apsCopy := *aps
+		payload["aps"] = &apsCopy
+		fields = append(fields, alertFields(&apsCopy)...)
+	}
+	for _, k := range customKeys {
+		k := k
+		if v, ok := payload[k].(string); ok {
+			fields = append(fields, truncatable{v, func(s string) { payload[k] = s }})
+		}
+	}
+
+	for _, f := range fields {
+		ok, err := n.truncate(f)
+		if err != nil {
+			n.Payload = original
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	n.Payload = original
+	return ErrPayloadCannotFit
+}
+
+// alertFields returns body and subtitle of the alert of aps,
+// the alert is copied to keep the original one.
+func alertFields(aps *APS) []truncatable {
+	var alert Alert
+	switch v := aps.Alert.(type) {
+	case string:
+		return []truncatable{{v, func(s string) { aps.Alert = s }}}
+	case *Alert:
+		if v == nil {
+			return nil
+		}
+		alert = *v
+	case Alert:
+		alert = v
+	default:
+		return nil
+	}
+	aps.Alert = &alert
+	return []truncatable{
+		{alert.Body, func(s string) { alert.Body = s }},
+		{alert.Subtitle, func(s string) { alert.Subtitle = s }},
+	}
+}
+
+// truncate cuts f to the longest prefix with which the payload fits.
+// Reports whether the payload fits.
+func (n *Notification) truncate(f truncatable) (bool, error) {
+	if f.value == "" {
+		return false, nil
+	}
+
+	bounds := graphemeBoundaries(f.value)
+	// Find the longest prefix that fits, the payload doesn't fit with the whole value.
+	lo, hi := 0, len(bounds)-1
+	found := -1
+	for lo < hi {
+		mid := (lo + hi) / 2
+		f.set(withEllipsis(f.value[:bounds[mid]]))
+		ok, err := n.payloadFits()
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			found = mid
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+
+	if found < 0 {
+		f.set("")
+		return n.payloadFits()
+	}
+	f.set(withEllipsis(f.value[:bounds[found]]))
+	return true, nil
+}
+
+func (n *Notification) payloadFits() (bool, error) {
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return false, err
+	}
+	return len(b) <= n.MaxPayloadSize(), nil
+}
+
+func withEllipsis(s string) string {
+	return strings.TrimRightFunc(s, unicode.IsSpace) + Ellipsis
+}
+
+// graphemeBoundaries returns byte offsets of s from 0 to len(s)
+// at which s can be cut without splitting a user-perceived character:
+// combining marks, variation selectors, emoji modifiers and tags
+// stay with their base, emoji joined by ZWJ and regional indicator pairs stay together.
+func graphemeBoundaries(s string) []int {
+	bounds := []int{0}
+	var prev rune
+	regional := 0 // regional indicators in a row
+	for i, r := range s {
+		if i > 0 && !extendsGrapheme(prev, r, regional) {
+			bounds = append(bounds, i)
+		}
+		if isRegionalIndicator(r) {
+			regional++
+		} else {
+			regional = 0
+		}
+		prev = r
+	}
+	if len(s) > 0 {
+		bounds = append(bounds, len(s))
+	}
+	return bounds
+}
+
+// extendsGrapheme reports whether r continues the grapheme cluster ending with prev.
+func extendsGrapheme(prev, r rune, regional int) bool {
+	switch {
+	case prev == '\r' && r == '\n':
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == '\u200d' || prev == '\u200d': // zero width joiner
+		return true
+	case r >= 0xfe00 && r <= 0xfe0f, r >= 0xe0100 && r <= 0xe01ef: // variation selectors
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // emoji modifiers
+		return true
+	case r >= 0xe0020 && r <= 0xe007f: // tags
+		return true
+	case isRegionalIndicator(r) && isRegionalIndicator(prev):
+		return regional%2 == 1
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
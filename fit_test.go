@@ -0,0 +1,166 @@
+package apns
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFitPayload(t *testing.T) {
+	alert := &Alert{
+		Title:    "Title",
+		Subtitle: "Subtitle",
+		Body:     strings.Repeat("Body ", 1000),
+	}
+	n := &Notification{Payload: BuildPayload(&APS{Alert: alert}, map[string]interface{}{"id": 1})}
+
+	if err := n.FitPayload(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := n.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) > MaxPayloadSize || len(b) < MaxPayloadSize-10 {
+		t.Errorf("len(payload): %v; want close to: %v", len(b), MaxPayloadSize)
+	}
+
+	fitted := n.Payload.(map[string]interface{})["aps"].(*APS).Alert.(*Alert)
+	if !strings.HasSuffix(fitted.Body, "Body"+Ellipsis) && !strings.HasSuffix(fitted.Body, "Bo"+Ellipsis) &&
+		!strings.HasSuffix(fitted.Body, Ellipsis) {
+		t.Errorf("body must end with ellipsis: %q", fitted.Body[len(fitted.Body)-10:])
+	}
+	if strings.HasSuffix(fitted.Body, " "+Ellipsis) {
+		t.Errorf("body must not end with space before ellipsis: %q", fitted.Body[len(fitted.Body)-10:])
+	}
+	if fitted.Subtitle != "Subtitle" || fitted.Title != "Title" {
+		t.Errorf("subtitle and title must be kept: %q, %q", fitted.Subtitle, fitted.Title)
+	}
+	if len(alert.Body) != 5000 {
+		t.Error("original alert must not be modified")
+	}
+}
+
+func TestFitPayloadFits(t *testing.T) {
+	payload := BuildPayload(&APS{Alert: "Hello"}, nil)
+	n := &Notification{Payload: payload}
+	if err := n.FitPayload(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(n.Payload, payload) {
+		t.Errorf("payload must not be changed: %v", n.Payload)
+	}
+
+	// VoIP payload limit is larger.
+	n = &Notification{
+		PushType: PushTypeVoIP,
+		Payload:  BuildPayload(&APS{Alert: strings.Repeat("x", MaxPayloadSize)}, nil),
+	}
+	if err := n.FitPayload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(n.Payload.(map[string]interface{})["aps"].(*APS).Alert.(string)) != MaxPayloadSize {
+		t.Error("VoIP payload must not be truncated")
+	}
+}
+
+func TestFitPayloadOrder(t *testing.T) {
+	large := strings.Repeat("x", MaxPayloadSize)
+
+	// Body is removed, subtitle is truncated.
+	n := &Notification{Payload: BuildPayload(&APS{
+		Alert: Alert{Subtitle: large, Body: large},
+	}, map[string]interface{}{"text": "Text"})}
+	if err := n.FitPayload("text"); err != nil {
+		t.Fatal(err)
+	}
+	alert := n.Payload.(map[string]interface{})["aps"].(*APS).Alert.(*Alert)
+	if alert.Body != "" {
+		t.Errorf("body must be removed: %v", len(alert.Body))
+	}
+	if !strings.HasSuffix(alert.Subtitle, Ellipsis) {
+		t.Error("subtitle must be truncated")
+	}
+	if n.Payload.(map[string]interface{})["text"] != "Text" {
+		t.Error("custom key must be kept")
+	}
+
+	// Custom keys are truncated in the given order.
+	n = &Notification{Payload: BuildPayload(&APS{Alert: Alert{Title: "Hello"}}, map[string]interface{}{
+		"first":  large,
+		"second": large,
+		"other":  1,
+	})}
+	if err := n.FitPayload("second", "first"); err != nil {
+		t.Fatal(err)
+	}
+	p := n.Payload.(map[string]interface{})
+	if p["second"] != "" || !strings.HasSuffix(p["first"].(string), Ellipsis) {
+		t.Errorf("custom keys: %v, %v", len(p["second"].(string)), len(p["first"].(string)))
+	}
+	if p["aps"].(*APS).Alert.(*Alert).Title != "Hello" {
+		t.Errorf("title: %v; want: Hello", p["aps"].(*APS).Alert.(*Alert).Title)
+	}
+}
+
+func TestFitPayloadCannotFit(t *testing.T) {
+	large := strings.Repeat("x", MaxPayloadSize)
+
+	payloads := []interface{}{
+		`{"aps":{"alert":"` + large + `"}}`,
+		BuildPayload(&APS{Alert: "Hello"}, map[string]interface{}{"data": large}),
+	}
+	for _, payload := range payloads {
+		n := &Notification{Payload: payload}
+		if err := n.FitPayload(); err != ErrPayloadCannotFit {
+			t.Errorf("err: %v; want: %v", err, ErrPayloadCannotFit)
+		}
+		if !reflect.DeepEqual(n.Payload, payload) {
+			t.Error("payload must be kept")
+		}
+	}
+}
+
+func TestFitPayloadGraphemes(t *testing.T) {
+	clusters := []string{
+		"e\u0301",              // e with combining acute accent
+		"\U0001F44D\U0001F3FD", // thumbs up with skin tone
+		"\U0001F468\u200d\U0001F469\u200d\U0001F467", // family joined by ZWJ
+		"\U0001F1FA\U0001F1F8",                       // flag
+		"\u2764\ufe0f",                               // heart with variation selector
+		"\u65e5",
+	}
+	for _, c := range clusters {
+		n := &Notification{Payload: BuildPayload(&APS{
+			Alert: strings.Repeat(c, MaxPayloadSize),
+		}, nil)}
+		if err := n.FitPayload(); err != nil {
+			t.Fatal(err)
+		}
+		alert := n.Payload.(map[string]interface{})["aps"].(*APS).Alert.(string)
+		body := strings.TrimSuffix(alert, Ellipsis)
+		if !utf8.ValidString(alert) || strings.Replace(body, c, "", -1) != "" || body == "" {
+			t.Errorf("%q: cluster is split: %q", c, body[len(body)-len(c):])
+		}
+	}
+}
+
+func TestGraphemeBoundaries(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []int
+	}{
+		{"", []int{0}},
+		{"ab", []int{0, 1, 2}},
+		{"\r\n", []int{0, 2}},
+		{"e\u0301x", []int{0, 3, 4}},
+		{"\U0001F1FA\U0001F1F8\U0001F1E9\U0001F1EA", []int{0, 8, 16}},
+	}
+	for _, test := range tests {
+		got := graphemeBoundaries(test.s)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q: got: %v; want: %v", test.s, got, test.want)
+		}
+	}
+}
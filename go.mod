@@ -1,3 +1,5 @@
 module github.com/bergusman/apns-go
 
 go 1.16
+
+require software.sslmate.com/src/go-pkcs12 v0.2.0
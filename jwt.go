@@ -1,14 +1,19 @@
 package apns
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/asn1"
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
+	"strings"
+	"time"
 )
 
 // See "Create and Encrypt Your JSON Token" section in
@@ -17,6 +22,16 @@ import (
 var (
 	// Private key is not ECDSA key with P-256 curve.
 	ErrJWTKeyNotECDSAP256 = errors.New("jwt: not ECDSA P-256 key")
+
+	// Signer returned signature that is neither ASN.1 DER nor raw r||s ECDSA P-256 signature,
+	// or signature of bearer can't be verified by public key.
+	ErrJWTBadSignature = errors.New("jwt: invalid signature")
+
+	// Bearer is not JWS Compact Serialization of JSON token.
+	ErrJWTBadBearer = errors.New("jwt: invalid bearer")
+
+	// Bearer is signed with other algorithm than ES256.
+	ErrJWTBadAlgorithm = errors.New("jwt: algorithm is not ES256")
 )
 
 // i2osp is an I2OSP (Integer to Octet Stream Primitive) function.
@@ -47,19 +62,198 @@ func es256(key *ecdsa.PrivateKey, input []byte) ([]byte, error) {
 	return append(i2osp(r), i2osp(s)...), nil
 }
 
+// es256Signer signs an input (JWS Signing Input) by signer
+// with ECDSA P-256 public key, for example, a key held in KMS or HSM.
+// Converts ASN.1 DER signature returned by signer to raw r||s form.
+// Returns ErrTokenKeyNil if signer or its private key is nil.
+func es256Signer(signer crypto.Signer, input []byte) ([]byte, error) {
+	if signer == nil {
+		return nil, ErrTokenKeyNil
+	}
+	if key, ok := signer.(*ecdsa.PrivateKey); ok {
+		if key == nil {
+			return nil, ErrTokenKeyNil
+		}
+		return es256(key, input)
+	}
+
+	pub, ok := signer.Public().(*ecdsa.PublicKey)
+	if ok && pub == nil {
+		return nil, ErrTokenKeyNil
+	}
+	if !ok || pub.Curve != elliptic.P256() {
+		return nil, ErrJWTKeyNotECDSAP256
+	}
+
+	digest := sha256.Sum256(input)
+	sig, err := signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return rawSignature(sig)
+}
+
+// rawSignature converts ECDSA P-256 signature in ASN.1 DER form,
+// that crypto.Signer returns, to raw r||s form, that JWS requires.
+// Signature already in raw form is returned as is.
+func rawSignature(sig []byte) ([]byte, error) {
+	var esig struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(sig, &esig)
+	if err != nil || len(rest) > 0 {
+		if len(sig) == 64 {
+			return sig, nil
+		}
+		return nil, ErrJWTBadSignature
+	}
+	if esig.R.Sign() <= 0 || esig.S.Sign() <= 0 || esig.R.BitLen() > 256 || esig.S.BitLen() > 256 {
+		return nil, ErrJWTBadSignature
+	}
+	return append(i2osp(esig.R), i2osp(esig.S)...), nil
+}
+
 // GenerateBearer creates JSON token with keyID, teamID and issuedAt and
 // encrypts by an authentication token signing key with the ES256 algorithm.
 // Returns encrypted token that used in the authorization header of a notification request
 // as bearer <token data>.
 func GenerateBearer(key *ecdsa.PrivateKey, keyID, teamID string, issuedAt int64) (string, error) {
+	return GenerateBearerWithSigner(key, keyID, teamID, issuedAt)
+}
+
+// GenerateBearerWithSigner is like GenerateBearer
+// but signs the token by signer with ECDSA P-256 public key,
+// so the authentication token signing key can be held in KMS, Vault transit or HSM.
+// Returns ErrTokenKeyNil for nil signer.
+func GenerateBearerWithSigner(signer crypto.Signer, keyID, teamID string, issuedAt int64) (string, error) {
 	// See RFC 7519 for JWT and RFC 7515 for JWS.
 	header := fmt.Sprintf(`{"alg":"ES256","typ":"JWT","kid":"%s"}`, keyID) // JOSE Header (JWT Protected Header)
 	payload := fmt.Sprintf(`{"iss":"%s","iat":%d}`, teamID, issuedAt)      // JWT Claims (JWS Payload)
 	unsecured := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." + base64.RawURLEncoding.EncodeToString([]byte(payload))
-	sig, err := es256(key, []byte(unsecured)) // JWS Signature
+	sig, err := es256Signer(signer, []byte(unsecured)) // JWS Signature
 	if err != nil {
 		return "", err
 	}
 	t := unsecured + "." + base64.RawURLEncoding.EncodeToString([]byte(sig)) // JWS Compact Serialization
 	return t, nil
 }
+
+// Bearer is a decoded provider token.
+type Bearer struct {
+	// The algorithm of JOSE Header, ES256 for provider tokens.
+	Algorithm string // alg
+
+	// The Key ID of authentication token signing key.
+	KeyID string // kid
+
+	// The Team ID of the issuer.
+	TeamID string // iss
+
+	// Time at the token was generated.
+	IssuedAt int64 // iat, Epoch time in seconds
+
+	// JWS Signing Input and JWS Signature.
+	Unsecured string
+	Signature []byte
+}
+
+// ParseBearer decodes bearer generated by GenerateBearer,
+// the value of the authorization header with bearer prefix is accepted too.
+// Signature of bearer is not verified, use Bearer.Verify.
+func ParseBearer(bearer string) (*Bearer, error) {
+	if len(bearer) > 7 && strings.EqualFold(bearer[:7], "bearer ") {
+		bearer = bearer[7:]
+	}
+
+	parts := strings.Split(bearer, ".")
+	if len(parts) != 3 {
+		return nil, ErrJWTBadBearer
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+
+	var claims struct {
+		Iss string `json:"iss"`
+		Iat int64  `json:"iat"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrJWTBadBearer
+	}
+
+	return &Bearer{
+		Algorithm: header.Alg,
+		KeyID:     header.Kid,
+		TeamID:    claims.Iss,
+		IssuedAt:  claims.Iat,
+		Unsecured: parts[0] + "." + parts[1],
+		Signature: sig,
+	}, nil
+}
+
+// decodeSegment decodes base64url encoded JSON segment of bearer to v.
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrJWTBadBearer
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return ErrJWTBadBearer
+	}
+	return nil
+}
+
+// Verify verifies ES256 signature of the bearer by public key
+// of authentication token signing key.
+// Returns ErrJWTBadAlgorithm if the bearer is not signed with ES256,
+// ErrJWTKeyNotECDSAP256 if key is not ECDSA P-256 key
+// and ErrJWTBadSignature if signature is invalid.
+func (b *Bearer) Verify(key *ecdsa.PublicKey) error {
+	if b.Algorithm != "ES256" {
+		return ErrJWTBadAlgorithm
+	}
+	if key == nil || key.Curve != elliptic.P256() {
+		return ErrJWTKeyNotECDSAP256
+	}
+	if len(b.Signature) != 64 {
+		return ErrJWTBadSignature
+	}
+	h := sha256.Sum256([]byte(b.Unsecured))
+	r := new(big.Int).SetBytes(b.Signature[:32])
+	s := new(big.Int).SetBytes(b.Signature[32:])
+	if !ecdsa.Verify(key, h[:], r, s) {
+		return ErrJWTBadSignature
+	}
+	return nil
+}
+
+// Time returns IssuedAt as time.Time.
+func (b *Bearer) Time() time.Time {
+	return time.Unix(b.IssuedAt, 0)
+}
+
+// Age returns time passed from IssuedAt to now.
+func (b *Bearer) Age(now time.Time) time.Duration {
+	return now.Sub(b.Time())
+}
+
+// NeedsRefresh reports whether the bearer is older than TokenRefreshInterval at now.
+func (b *Bearer) NeedsRefresh(now time.Time) bool {
+	return now.Unix() > b.IssuedAt+TokenRefreshInterval
+}
+
+// Expired reports whether the bearer is older than TokenMaxAge at now,
+// APNs rejects such bearer with ExpiredProviderToken.
+func (b *Bearer) Expired(now time.Time) bool {
+	return now.Unix() > b.IssuedAt+TokenMaxAge
+}
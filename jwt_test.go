@@ -1,13 +1,19 @@
 package apns
 
 import (
+	"bytes"
+	"crypto"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"encoding/asn1"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
 	"math/big"
 	"strings"
 	"testing"
@@ -172,3 +178,308 @@ func BenchmarkGenerateBearer(b *testing.B) {
 		GenerateBearer(key, keyID, teamID, issuedAt)
 	}
 }
+
+// derSigner wraps a key to sign as an external crypto.Signer
+// returning ASN.1 DER signatures, like KMS or PKCS #11 HSM.
+type derSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s derSigner) Public() crypto.PublicKey {
+	return &s.key.PublicKey
+}
+
+func (s derSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, digest, opts)
+}
+
+// rawSigner returns raw r||s signatures.
+type rawSigner struct {
+	derSigner
+}
+
+func (s rawSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	r, ss, err := ecdsa.Sign(rand, s.key, digest)
+	if err != nil {
+		return nil, err
+	}
+	return append(i2osp(r), i2osp(ss)...), nil
+}
+
+func TestES256Signer(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input := []byte("input")
+	digest := sha256.Sum256(input)
+
+	signers := []crypto.Signer{derSigner{key}, rawSigner{derSigner{key}}}
+	for _, signer := range signers {
+		sig, err := es256Signer(signer, input)
+		if err != nil {
+			t.Fatalf("%T: %v", signer, err)
+		}
+		if len(sig) != 64 {
+			t.Fatalf("%T: len(sig): %v; want: 64", signer, len(sig))
+		}
+
+		r, s := new(big.Int), new(big.Int)
+		r.SetBytes(sig[:32])
+		s.SetBytes(sig[32:])
+		if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+			t.Errorf("%T: cannot verify ES256 signature", signer)
+		}
+	}
+}
+
+func TestES256SignerKeyNotP256(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = es256Signer(derSigner{key}, []byte("input"))
+	if err != ErrJWTKeyNotECDSAP256 {
+		t.Errorf("P-384: got: %v; want: %v", err, ErrJWTKeyNotECDSAP256)
+	}
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = es256Signer(rsaKey, []byte("input"))
+	if err != ErrJWTKeyNotECDSAP256 {
+		t.Errorf("RSA: got: %v; want: %v", err, ErrJWTKeyNotECDSAP256)
+	}
+}
+
+func TestRawSignature(t *testing.T) {
+	r, s := big.NewInt(1337), big.NewInt(42)
+	der, err := asn1.Marshal(struct{ R, S *big.Int }{r, s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(i2osp(r), i2osp(s)...)
+	sig, err := rawSignature(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Errorf("DER: got: %x; want: %x", sig, want)
+	}
+
+	sig, err = rawSignature(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sig, want) {
+		t.Errorf("raw: got: %x; want: %x", sig, want)
+	}
+
+	bad := [][]byte{
+		nil,
+		[]byte("signature"),
+		append(der, 0),
+	}
+	for _, b := range bad {
+		if _, err := rawSignature(b); err != ErrJWTBadSignature {
+			t.Errorf("%x: got: %v; want: %v", b, err, ErrJWTBadSignature)
+		}
+	}
+}
+
+func TestGenerateBearerWithSigner(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuedAt := time.Now().Unix()
+	bearer, err := GenerateBearerWithSigner(derSigner{key}, "5MDQ4KLTY7", "SUPERTEEM1", issuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(bearer, ".")
+	if len(parts) != 3 {
+		t.Fatal("token must have three parts spearated by dot")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sig) != 64 {
+		t.Fatalf("len(sig): %v; want: 64", len(sig))
+	}
+
+	r, s := new(big.Int), new(big.Int)
+	r.SetBytes(sig[:32])
+	s.SetBytes(sig[32:])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(&key.PublicKey, digest[:], r, s) {
+		t.Error("cannot verify ES256 signature")
+	}
+}
+
+func TestGenerateBearerKeyNil(t *testing.T) {
+	var key *ecdsa.PrivateKey
+	signers := []crypto.Signer{nil, key, nilPublicSigner{}}
+	for _, signer := range signers {
+		_, err := GenerateBearerWithSigner(signer, "5MDQ4KLTY7", "SUPERTEEM1", 0)
+		if err != ErrTokenKeyNil {
+			t.Errorf("%T: got: %v; want: %v", signer, err, ErrTokenKeyNil)
+		}
+	}
+	_, err := GenerateBearer(nil, "5MDQ4KLTY7", "SUPERTEEM1", 0)
+	if err != ErrTokenKeyNil {
+		t.Errorf("GenerateBearer: got: %v; want: %v", err, ErrTokenKeyNil)
+	}
+}
+
+// nilPublicSigner has nil ECDSA public key.
+type nilPublicSigner struct{}
+
+func (nilPublicSigner) Public() crypto.PublicKey {
+	var pub *ecdsa.PublicKey
+	return pub
+}
+
+func (nilPublicSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("must not be called")
+}
+
+func TestParseBearer(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	issuedAt := time.Now().Unix()
+	bearer, err := GenerateBearer(key, "5MDQ4KLTY7", "SUPERTEEM1", issuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, s := range []string{bearer, "bearer " + bearer, "Bearer " + bearer} {
+		b, err := ParseBearer(s)
+		if err != nil {
+			t.Fatalf("%v: %v", s, err)
+		}
+		if b.Algorithm != "ES256" {
+			t.Errorf("b.Algorithm: %v; want: %v", b.Algorithm, "ES256")
+		}
+		if b.KeyID != "5MDQ4KLTY7" {
+			t.Errorf("b.KeyID: %v; want: %v", b.KeyID, "5MDQ4KLTY7")
+		}
+		if b.TeamID != "SUPERTEEM1" {
+			t.Errorf("b.TeamID: %v; want: %v", b.TeamID, "SUPERTEEM1")
+		}
+		if b.IssuedAt != issuedAt {
+			t.Errorf("b.IssuedAt: %v; want: %v", b.IssuedAt, issuedAt)
+		}
+		if err := b.Verify(&key.PublicKey); err != nil {
+			t.Errorf("Verify: %v", err)
+		}
+	}
+}
+
+func TestParseBearerInvalid(t *testing.T) {
+	bearers := []string{
+		"",
+		"bearer",
+		"header.claims",
+		"header.claims.signature.extra",
+		"!.e30.c2ln",
+		"e30.!.c2ln",
+		"e30.e30.!",
+		"bm90IGpzb24.e30.c2ln",
+	}
+	for _, b := range bearers {
+		if _, err := ParseBearer(b); err != ErrJWTBadBearer {
+			t.Errorf("%q: err: %v; want: %v", b, err, ErrJWTBadBearer)
+		}
+	}
+}
+
+func TestBearerVerify(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bearer, err := GenerateBearer(key, "5MDQ4KLTY7", "SUPERTEEM1", time.Now().Unix())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseBearer(bearer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := b.Verify(&other.PublicKey); err != ErrJWTBadSignature {
+		t.Errorf("other key: err: %v; want: %v", err, ErrJWTBadSignature)
+	}
+	if err := b.Verify(&p384.PublicKey); err != ErrJWTKeyNotECDSAP256 {
+		t.Errorf("P-384 key: err: %v; want: %v", err, ErrJWTKeyNotECDSAP256)
+	}
+	if err := b.Verify(nil); err != ErrJWTKeyNotECDSAP256 {
+		t.Errorf("nil key: err: %v; want: %v", err, ErrJWTKeyNotECDSAP256)
+	}
+
+	tampered := *b
+	tampered.TeamID = "OTHERTEAM1"
+	tampered.Unsecured = strings.Split(bearer, ".")[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"OTHERTEAM1","iat":0}`))
+	if err := tampered.Verify(&key.PublicKey); err != ErrJWTBadSignature {
+		t.Errorf("tampered: err: %v; want: %v", err, ErrJWTBadSignature)
+	}
+
+	hs256 := *b
+	hs256.Algorithm = "HS256"
+	if err := hs256.Verify(&key.PublicKey); err != ErrJWTBadAlgorithm {
+		t.Errorf("HS256: err: %v; want: %v", err, ErrJWTBadAlgorithm)
+	}
+}
+
+func TestBearerAge(t *testing.T) {
+	issuedAt := time.Date(2021, 8, 15, 12, 0, 0, 0, time.UTC)
+	b := &Bearer{IssuedAt: issuedAt.Unix()}
+
+	if !b.Time().Equal(issuedAt) {
+		t.Errorf("Time: %v; want: %v", b.Time(), issuedAt)
+	}
+
+	tests := []struct {
+		age          time.Duration
+		needsRefresh bool
+		expired      bool
+	}{
+		{0, false, false},
+		{TokenRefreshInterval * time.Second, false, false},
+		{(TokenRefreshInterval + 1) * time.Second, true, false},
+		{TokenMaxAge * time.Second, true, false},
+		{(TokenMaxAge + 1) * time.Second, true, true},
+	}
+	for _, test := range tests {
+		now := issuedAt.Add(test.age)
+		if b.Age(now) != test.age {
+			t.Errorf("Age: %v; want: %v", b.Age(now), test.age)
+		}
+		if b.NeedsRefresh(now) != test.needsRefresh {
+			t.Errorf("%v: NeedsRefresh: %v; want: %v", test.age, b.NeedsRefresh(now), test.needsRefresh)
+		}
+		if b.Expired(now) != test.expired {
+			t.Errorf("%v: Expired: %v; want: %v", test.age, b.Expired(now), test.expired)
+		}
+	}
+}
@@ -1,13 +1,17 @@
-// APNS package for sending remote notifications for iOS by token-based connection.
+// APNS package for sending remote notifications for iOS by token-based or certificate-based connection.
 package apns
 
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // See https://developer.apple.com/documentation/usernotifications/setting_up_a_remote_notification_server/sending_notification_requests_to_apns.
@@ -102,6 +106,17 @@ const (
 	// The mdm push type is not available on watchOS.
 	// It is recommended on macOS, iOS, tvOS, and iPadOS.
 	PushTypeMDM = "mdm"
+
+	// Use the liveactivity push type for notifications that start, update or end
+	// a Live Activity of ActivityKit. For more information,
+	// see https://developer.apple.com/documentation/activitykit/starting-and-updating-live-activities-with-activitykit-push-notifications.
+	//
+	// If you set this push type, the apns-topic header field
+	// must use your app’s bundle ID with .push-type.liveactivity appended to the end.
+	// Use APS fields Event, Timestamp, ContentState and others for the payload.
+	//
+	// The liveactivity push type is available on iOS and iPadOS 16.1 and later.
+	PushTypeLiveActivity = "liveactivity"
 )
 
 // Values for Notification.Priority field.
@@ -111,6 +126,28 @@ const (
 
 	// Specify 5 to send the notification based on power considerations on the user’s device.
 	PriorityLow = 5
+
+	// Specify 1 to prioritize the device’s power considerations over all other factors
+	// for delivery, and prevent awakening the device.
+	PriorityPowerSaving = 1
+)
+
+// Value for Notification.Expiration field.
+// APNs attempts to deliver the notification only once and doesn’t store it.
+// It differs from omitted Expiration, then APNs stores the notification
+// according to its own policy.
+const ExpirationDeliverOnce = "0"
+
+// Size limits of notification request values.
+const (
+	// Maximum size of the JSON payload in bytes.
+	MaxPayloadSize = 4096
+
+	// Maximum size of the JSON payload for a Voice over Internet Protocol (VoIP) notification.
+	MaxVoIPPayloadSize = 5120
+
+	// Maximum size of the apns-collapse-id value in bytes.
+	MaxCollapseIDSize = 64
 )
 
 type Notification struct {
@@ -120,6 +157,13 @@ type Notification struct {
 	// see https://developer.apple.com/documentation/usernotifications/registering_your_app_with_apns.
 	DeviceToken string
 
+	// The base64 encoded identifier of a broadcast channel, see ChannelClient.
+	// If set, the notification is broadcast to all devices subscribed to the channel
+	// instead of DeviceToken, and Topic must be your app’s bundle ID
+	// (the .push-type.liveactivity suffix is allowed).
+	// Use SetChannel to respect the channel’s message storage policy.
+	ChannelID string // header: apns-channel-id
+
 	Host string
 
 	// A canonical UUID that is the unique ID for the notification.
@@ -156,12 +200,14 @@ type Notification struct {
 	// repeating the attempt as needed until the specified date.
 	// If the value is 0, APNs attempts to deliver
 	// the notification only once and doesn’t store it.
+	// Use SetExpiration, SetExpirationAfter or SetDeliverOnce to set it.
 	Expiration string // header: apns-expiration
 
 	// The priority of the notification.
 	// If you omit this header, APNs sets the notification priority to 10.
 	// Specify 10 to send the notification immediately.
 	// Specify 5 to send the notification based on power considerations on the user’s device.
+	// Specify 1 to prioritize the device’s power considerations and prevent awakening the device.
 	Priority int // header: apns-priority
 
 	// An identifier you use to coalesce multiple notifications
@@ -181,6 +227,19 @@ type Notification struct {
 	Payload interface{}
 }
 
+// NewID returns a random canonical UUID (version 4) in upper case
+// for Notification.ID field.
+func NewID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant RFC 4122
+	h := strings.ToUpper(hex.EncodeToString(b[:]))
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
 // MarshalJSON marshals the notification payload to JSON.
 func (n *Notification) MarshalJSON() ([]byte, error) {
 	switch v := n.Payload.(type) {
@@ -195,10 +254,91 @@ func (n *Notification) MarshalJSON() ([]byte, error) {
 
 // The path to the device token.
 // The value of this header is /3/device/<device_token>.
+// For broadcast notification with ChannelID the value is /4/broadcasts/apps/<bundle_id>.
 func (n *Notification) Path() string {
+	if n.ChannelID != "" {
+		return "/4/broadcasts/apps/" + n.BundleID()
+	}
 	return "/3/device/" + n.DeviceToken
 }
 
+// BundleID returns Topic without the push type suffix,
+// for example, .voip or .push-type.liveactivity.
+func (n *Notification) BundleID() string {
+	for _, suffix := range topicSuffixes {
+		if strings.HasSuffix(n.Topic, suffix) {
+			return strings.TrimSuffix(n.Topic, suffix)
+		}
+	}
+	return n.Topic
+}
+
+// SetChannel sets ChannelID to broadcast the notification to ch.
+// APNs doesn't store messages of channel with MessageStoragePolicyNone,
+// so Expiration is set to 0 for it.
+func (n *Notification) SetChannel(ch *Channel) {
+	n.ChannelID = ch.ID
+	if ch.MessageStoragePolicy == MessageStoragePolicyNone {
+		n.Expiration = ExpirationDeliverOnce
+	}
+}
+
+// SetExpiration sets Expiration to t as UNIX epoch in seconds,
+// zero t omits the apns-expiration header.
+func (n *Notification) SetExpiration(t time.Time) {
+	if t.IsZero() {
+		n.Expiration = ""
+		return
+	}
+	n.Expiration = strconv.FormatInt(t.Unix(), 10)
+}
+
+// SetExpirationAfter sets Expiration to d after the current time of clock,
+// pass nil for clock to use the system time.
+// If d is 0 or less, the notification is delivered only once, see SetDeliverOnce.
+func (n *Notification) SetExpirationAfter(d time.Duration, clock Clock) {
+	if d <= 0 {
+		n.SetDeliverOnce()
+		return
+	}
+	n.SetExpiration(now(clock).Add(d))
+}
+
+// SetDeliverOnce sets Expiration to ExpirationDeliverOnce,
+// so APNs attempts to deliver the notification only once and doesn’t store it.
+func (n *Notification) SetDeliverOnce() {
+	n.Expiration = ExpirationDeliverOnce
+}
+
+// DeliverOnce reports whether Expiration is ExpirationDeliverOnce.
+func (n *Notification) DeliverOnce() bool {
+	return n.Expiration == ExpirationDeliverOnce
+}
+
+// ExpirationTime returns Expiration as time.Time.
+// Returns zero time if Expiration is omitted, ExpirationDeliverOnce or invalid.
+func (n *Notification) ExpirationTime() time.Time {
+	e, err := strconv.ParseInt(n.Expiration, 10, 64)
+	if err != nil || e <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(e, 0)
+}
+
+// ExpiresIn returns time left until Expiration from the current time of clock,
+// pass nil for clock to use the system time.
+// Returns 0 if Expiration is omitted, ExpirationDeliverOnce, invalid or passed.
+func (n *Notification) ExpiresIn(clock Clock) time.Duration {
+	t := n.ExpirationTime()
+	if t.IsZero() {
+		return 0
+	}
+	if d := t.Sub(now(clock)); d > 0 {
+		return d
+	}
+	return 0
+}
+
 // URL builds full URL of remote notification request.
 // If Host field omitted HostProduction will be used.
 func (n *Notification) URL() string {
@@ -254,4 +394,7 @@ func (n *Notification) SetHeaders(h http.Header) {
 	if n.CollapseID != "" {
 		h.Set("apns-collapse-id", n.CollapseID)
 	}
+	if n.ChannelID != "" {
+		h.Set("apns-channel-id", n.ChannelID)
+	}
 }
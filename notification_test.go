@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNotificationPayload(t *testing.T) {
@@ -69,6 +71,80 @@ func TestNotificationPath(t *testing.T) {
 	}
 }
 
+func TestNotificationBroadcast(t *testing.T) {
+	n := &Notification{
+		DeviceToken: "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e",
+		Topic:       "com.example.app.push-type.liveactivity",
+		PushType:    PushTypeLiveActivity,
+	}
+	if n.BundleID() != "com.example.app" {
+		t.Errorf("bundle id: %v; want: %v", n.BundleID(), "com.example.app")
+	}
+
+	n.SetChannel(&Channel{ID: "dHN0LXNyY2gtY2hubA==", MessageStoragePolicy: MessageStoragePolicyMostRecent})
+	if n.Expiration != "" {
+		t.Errorf("expiration: %v; want empty", n.Expiration)
+	}
+	if n.Path() != "/4/broadcasts/apps/com.example.app" {
+		t.Errorf("got: %v; want: %v", n.Path(), "/4/broadcasts/apps/com.example.app")
+	}
+
+	n.SetChannel(&Channel{ID: "dHN0LXNyY2gtY2hubA==", MessageStoragePolicy: MessageStoragePolicyNone})
+	if n.Expiration != "0" {
+		t.Errorf("expiration: %v; want: 0", n.Expiration)
+	}
+
+	h := make(http.Header)
+	n.SetHeaders(h)
+	if h.Get("apns-channel-id") != "dHN0LXNyY2gtY2hubA==" {
+		t.Errorf("apns-channel-id: %v; want: %v", h.Get("apns-channel-id"), "dHN0LXNyY2gtY2hubA==")
+	}
+}
+
+func TestNotificationExpiration(t *testing.T) {
+	now := time.Date(2021, 8, 15, 12, 0, 0, 0, time.UTC)
+	clock := ClockFunc(func() time.Time { return now })
+	n := &Notification{}
+
+	if !n.ExpirationTime().IsZero() || n.DeliverOnce() || n.ExpiresIn(clock) != 0 {
+		t.Errorf("omitted expiration: %v, %v, %v", n.ExpirationTime(), n.DeliverOnce(), n.ExpiresIn(clock))
+	}
+
+	n.SetExpiration(now.Add(time.Hour))
+	if n.Expiration != "1629032400" {
+		t.Errorf("n.Expiration: %v; want: %v", n.Expiration, "1629032400")
+	}
+	if !n.ExpirationTime().Equal(now.Add(time.Hour)) {
+		t.Errorf("ExpirationTime: %v; want: %v", n.ExpirationTime(), now.Add(time.Hour))
+	}
+	if n.ExpiresIn(clock) != time.Hour {
+		t.Errorf("ExpiresIn: %v; want: %v", n.ExpiresIn(clock), time.Hour)
+	}
+
+	n.SetExpirationAfter(24*time.Hour, clock)
+	if n.Expiration != "1629115200" {
+		t.Errorf("n.Expiration: %v; want: %v", n.Expiration, "1629115200")
+	}
+
+	n.SetExpirationAfter(0, clock)
+	if n.Expiration != ExpirationDeliverOnce || !n.DeliverOnce() {
+		t.Errorf("n.Expiration: %v; want: %v", n.Expiration, ExpirationDeliverOnce)
+	}
+	if !n.ExpirationTime().IsZero() || n.ExpiresIn(clock) != 0 {
+		t.Errorf("deliver once: %v, %v", n.ExpirationTime(), n.ExpiresIn(clock))
+	}
+
+	n.SetExpiration(now.Add(-time.Hour))
+	if n.ExpiresIn(clock) != 0 {
+		t.Errorf("passed: ExpiresIn: %v; want: 0", n.ExpiresIn(clock))
+	}
+
+	n.SetExpiration(time.Time{})
+	if n.Expiration != "" {
+		t.Errorf("n.Expiration: %q; want empty", n.Expiration)
+	}
+}
+
 func TestNotificationURL(t *testing.T) {
 	n := &Notification{}
 	if n.URL() != "https://api.push.apple.com/3/device/" {
@@ -239,3 +315,19 @@ func BenchmarkNotificationFullPayload(b *testing.B) {
 		json.Marshal(n)
 	}
 }
+
+func TestNewID(t *testing.T) {
+	id := NewID()
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+		t.Errorf("not canonical UUID: %v", id)
+	}
+	if id[14] != '4' {
+		t.Errorf("not version 4 UUID: %v", id)
+	}
+	if id != strings.ToUpper(id) {
+		t.Errorf("not upper case UUID: %v", id)
+	}
+	if NewID() == id {
+		t.Error("IDs must be different")
+	}
+}
@@ -24,6 +24,18 @@ const (
 	InterruptionLevelTimeSensitive = "time-senstive"
 )
 
+// Values for APS.Event field of Live Activity notification.
+const (
+	// Start a Live Activity, requires AttributesType and Attributes.
+	LiveActivityEventStart = "start"
+
+	// Update the content of an ongoing Live Activity.
+	LiveActivityEventUpdate = "update"
+
+	// End an ongoing Live Activity.
+	LiveActivityEventEnd = "end"
+)
+
 // APS represents Apple-defined remote notification payload keys and their custom values.
 type APS struct {
 	// String or Alert struct.
@@ -88,6 +100,38 @@ type APS struct {
 	RelevanceScore interface{} `json:"relevance-score,omitempty"`
 
 	URLArgs []string `json:"url-args,omitempty"`
+
+	// The UNIX timestamp in seconds that marks the time when you send
+	// the notification that starts, updates or ends a Live Activity.
+	// The system ignores updates with older timestamps.
+	Timestamp int64 `json:"timestamp,omitempty"`
+
+	// The string that describes whether you start, update or end a Live Activity:
+	// LiveActivityEventStart, LiveActivityEventUpdate or LiveActivityEventEnd.
+	Event string `json:"event,omitempty"`
+
+	// Struct, map or nil.
+	// The updated or final content of a Live Activity.
+	// It must match the ContentState of your ActivityAttributes implementation,
+	// use a struct with the same JSON keys for type safety.
+	ContentState interface{} `json:"content-state,omitempty"`
+
+	// The UNIX timestamp in seconds at which a Live Activity becomes stale
+	// and the system shows it as outdated.
+	StaleDate int64 `json:"stale-date,omitempty"`
+
+	// The UNIX timestamp in seconds at which the system removes an ended Live Activity
+	// from the Lock Screen. Specify a date in the past to remove it immediately.
+	DismissalDate int64 `json:"dismissal-date,omitempty"`
+
+	// The name of your ActivityAttributes implementation
+	// for the start event of a Live Activity.
+	AttributesType string `json:"attributes-type,omitempty"`
+
+	// Struct, map or nil.
+	// The static data of a Live Activity for the start event.
+	// It must match your ActivityAttributes implementation.
+	Attributes interface{} `json:"attributes,omitempty"`
 }
 
 // The information for displaying an alert.
@@ -65,3 +65,59 @@ func TestBuildPayload(t *testing.T) {
 		t.Errorf("Custom: %v; want: %v", string(p.Custom), `{"hello":"Go"}`)
 	}
 }
+
+func TestLiveActivityPayload(t *testing.T) {
+	type ContentState struct {
+		Score  int    `json:"score"`
+		Status string `json:"status"`
+	}
+	type Attributes struct {
+		Home string `json:"home"`
+		Away string `json:"away"`
+	}
+
+	tests := []struct {
+		aps  *APS
+		want string
+	}{
+		{
+			aps: &APS{
+				Timestamp:      1629000000,
+				Event:          LiveActivityEventStart,
+				ContentState:   ContentState{Score: 0, Status: "started"},
+				AttributesType: "MatchAttributes",
+				Attributes:     Attributes{Home: "Lions", Away: "Tigers"},
+				Alert:          Alert{Title: "Match started"},
+			},
+			want: `{"aps":{"alert":{"title":"Match started"},"timestamp":1629000000,"event":"start","content-state":{"score":0,"status":"started"},"attributes-type":"MatchAttributes","attributes":{"home":"Lions","away":"Tigers"}}}`,
+		},
+		{
+			aps: &APS{
+				Timestamp:    1629000060,
+				Event:        LiveActivityEventUpdate,
+				ContentState: ContentState{Score: 1, Status: "playing"},
+				StaleDate:    1629003600,
+			},
+			want: `{"aps":{"timestamp":1629000060,"event":"update","content-state":{"score":1,"status":"playing"},"stale-date":1629003600}}`,
+		},
+		{
+			aps: &APS{
+				Timestamp:     1629005400,
+				Event:         LiveActivityEventEnd,
+				ContentState:  ContentState{Score: 3, Status: "finished"},
+				DismissalDate: 1629009000,
+			},
+			want: `{"aps":{"timestamp":1629005400,"event":"end","content-state":{"score":3,"status":"finished"},"dismissal-date":1629009000}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		b, err := json.Marshal(BuildPayload(tt.aps, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("got: %v; want: %v", string(b), tt.want)
+		}
+	}
+}
@@ -159,6 +159,10 @@ type Response struct {
 	// APNs creates a new UUID and returns it in this header.
 	ID string // header: apns-id
 
+	// The apns-request-id of broadcast notification response.
+	// APNs uses it instead of apns-id for notifications sent to channels.
+	RequestID string // header: apns-request-id
+
 	// The HTTP status code.
 	Status int // header: :status
 
@@ -168,7 +172,12 @@ type Response struct {
 	// The time, represented in milliseconds since Epoch,
 	// at which APNs confirmed the token was no longer valid for the topic.
 	// This key is included only when the error in the :status field is 410.
+	// Use Time to get it as time.Time.
 	Timestamp int64 `json:"timestamp,omitempty"`
+
+	// The key ID of the token signed the request, set by Client.
+	// Empty for certificate-based connection.
+	KeyID string `json:"-"`
 }
 
 // ParseResponse parses HTTP response r from APNs request.
@@ -176,8 +185,9 @@ type Response struct {
 func ParseResponse(r *http.Response) (*Response, error) {
 	defer r.Body.Close()
 	res := &Response{
-		ID:     r.Header.Get("apns-id"),
-		Status: r.StatusCode,
+		ID:        r.Header.Get("apns-id"),
+		RequestID: r.Header.Get("apns-request-id"),
+		Status:    r.StatusCode,
 	}
 	if err := json.NewDecoder(r.Body).Decode(res); err != nil && err != io.EOF {
 		return nil, err
@@ -58,6 +58,23 @@ func TestParseResponseNot200(t *testing.T) {
 	}
 }
 
+func TestParseResponseRequestID(t *testing.T) {
+	r := &http.Response{
+		StatusCode: 200,
+		Header: http.Header{
+			"Apns-Request-Id": []string{"EC1BF194-B3B2-424A-89A9-5A918A6E6B5D"},
+		},
+		Body: io.NopCloser(strings.NewReader("")),
+	}
+	res, err := ParseResponse(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.RequestID != "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D" {
+		t.Errorf("res.RequestID: %v want: EC1BF194-B3B2-424A-89A9-5A918A6E6B5D", res.RequestID)
+	}
+}
+
 func TestParseResponseErrors(t *testing.T) {
 	r := &http.Response{
 		StatusCode: 410,
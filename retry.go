@@ -0,0 +1,169 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Default values of Backoff fields.
+const (
+	DefaultBackoffMaxAttempts = 5
+	DefaultBackoffMinDelay    = 100 * time.Millisecond
+	DefaultBackoffMaxDelay    = 10 * time.Second
+	DefaultBackoffJitter      = 0.5
+)
+
+// RetryPolicy decides whether a failed notification request should be sent again.
+// Client keeps the same apns-id across attempts
+// and never waits past the context deadline.
+type RetryPolicy interface {
+	// Retry is called after attempt (counting from 1) ended with response res or error err.
+	// Returns delay before the next attempt
+	// or false if the request must not be retried.
+	Retry(attempt int, res *Response, err error) (time.Duration, bool)
+}
+
+// Backoff is RetryPolicy that retries requests classified by Retryable
+// with exponential backoff and jitter.
+type Backoff struct {
+	// Maximum number of attempts including the first one.
+	// If zero, DefaultBackoffMaxAttempts is used.
+	MaxAttempts int
+
+	// Delay before the second attempt, the delay doubles for each next attempt.
+	// If zero, DefaultBackoffMinDelay is used.
+	MinDelay time.Duration
+
+	// Maximum delay between attempts.
+	// If zero, DefaultBackoffMaxDelay is used.
+	MaxDelay time.Duration
+
+	// Fraction of the delay, from 0 to 1, that is randomized.
+	// For example, 0.5 gives delays from 50% to 100% of the exponential delay.
+	Jitter float64
+}
+
+// NewBackoff returns Backoff with default values.
+func NewBackoff() *Backoff {
+	return &Backoff{
+		MaxAttempts: DefaultBackoffMaxAttempts,
+		MinDelay:    DefaultBackoffMinDelay,
+		MaxDelay:    DefaultBackoffMaxDelay,
+		Jitter:      DefaultBackoffJitter,
+	}
+}
+
+// Retry implements RetryPolicy.
+func (b *Backoff) Retry(attempt int, res *Response, err error) (time.Duration, bool) {
+	maxAttempts := b.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultBackoffMaxAttempts
+	}
+	if attempt >= maxAttempts || !Retryable(res, err) {
+		return 0, false
+	}
+	return b.Delay(attempt), true
+}
+
+// Delay returns delay after attempt (counting from 1) with applied jitter.
+func (b *Backoff) Delay(attempt int) time.Duration {
+	min, max := b.MinDelay, b.MaxDelay
+	if min <= 0 {
+		min = DefaultBackoffMinDelay
+	}
+	if max <= 0 {
+		max = DefaultBackoffMaxDelay
+	}
+
+	d := min
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+
+	if b.Jitter > 0 {
+		j := b.Jitter
+		if j > 1 {
+			j = 1
+		}
+		d -= time.Duration(rand.Float64() * j * float64(d))
+	}
+	return d
+}
+
+// Retryable reports whether notification request ended
+// with response res or transport error err is safe to send again.
+//
+// Retryable responses are Status 429 TooManyRequests,
+// Status 500, Status 503 and Status 400 IdleTimeout.
+// Retryable errors are timeouts, connection resets and closed connections,
+// including GOAWAY from the server. Context errors are not retryable.
+//
+// io.EOF and io.ErrUnexpectedEOF are not retryable: the connection closed
+// while waiting for the response means the request is possibly delivered,
+// and APNs doesn't promise to drop duplicates by apns-id,
+// so sending it again can deliver the notification twice.
+func Retryable(res *Response, err error) bool {
+	if err != nil {
+		return retryableError(err)
+	}
+	if res == nil {
+		return false
+	}
+	switch res.Status {
+	case Status400:
+		return res.Reason == ReasonIdleTimeout
+	case Status429:
+		return res.Reason == ReasonTooManyRequests
+	case Status500, Status503:
+		return true
+	}
+	return false
+}
+
+func retryableError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return false
+	}
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var ne net.Error
+	if errors.As(err, &ne) && ne.Timeout() {
+		return true
+	}
+	// HTTP/2 transport errors are not exported.
+	s := err.Error()
+	return strings.Contains(s, "GOAWAY") ||
+		strings.Contains(s, "connection lost") ||
+		strings.Contains(s, "connection reset") ||
+		strings.Contains(s, "use of closed network connection")
+}
+
+// sleep waits for d or until ctx is done.
+// Returns false if ctx is done or its deadline comes before d elapses.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(d).After(deadline) {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,183 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		res  *Response
+		err  error
+		want bool
+	}{
+		{&Response{Status: Status200}, nil, false},
+		{&Response{Status: Status400, Reason: ReasonBadDeviceToken}, nil, false},
+		{&Response{Status: Status400, Reason: ReasonIdleTimeout}, nil, true},
+		{&Response{Status: Status403, Reason: ReasonInvalidProviderToken}, nil, false},
+		{&Response{Status: Status410, Reason: ReasonUnregistered}, nil, false},
+		{&Response{Status: Status429, Reason: ReasonTooManyRequests}, nil, true},
+		{&Response{Status: Status429, Reason: ReasonTooManyProviderTokenUpdates}, nil, false},
+		{&Response{Status: Status500, Reason: ReasonInternalServerError}, nil, true},
+		{&Response{Status: Status503, Reason: ReasonServiceUnavailable}, nil, true},
+		{&Response{Status: Status503, Reason: ReasonShutdown}, nil, true},
+		{nil, nil, false},
+		{nil, io.EOF, false},
+		{nil, io.ErrUnexpectedEOF, false},
+		{nil, &url.Error{Op: "Post", URL: "https://api.push.apple.com", Err: io.ErrUnexpectedEOF}, false},
+		{nil, syscall.ECONNRESET, true},
+		{nil, errors.New("http2: server sent GOAWAY and closed the connection"), true},
+		{nil, context.Canceled, false},
+		{nil, context.DeadlineExceeded, false},
+		{nil, ErrJWTKeyNotECDSAP256, false},
+	}
+	for i, tt := range tests {
+		if got := Retryable(tt.res, tt.err); got != tt.want {
+			t.Errorf("%v: got: %v; want: %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := &Backoff{
+		MinDelay: 100 * time.Millisecond,
+		MaxDelay: time.Second,
+	}
+	want := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, w := range want {
+		if got := b.Delay(i + 1); got != w {
+			t.Errorf("attempt %v: got: %v; want: %v", i+1, got, w)
+		}
+	}
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := b.Delay(3)
+		if d < 200*time.Millisecond || d > 400*time.Millisecond {
+			t.Fatalf("got: %v; want from 200ms to 400ms", d)
+		}
+	}
+}
+
+func TestBackoffRetry(t *testing.T) {
+	b := &Backoff{MaxAttempts: 3}
+	res := &Response{Status: Status503, Reason: ReasonShutdown}
+	if _, ok := b.Retry(1, res, nil); !ok {
+		t.Error("attempt 1 must be retried")
+	}
+	if _, ok := b.Retry(3, res, nil); ok {
+		t.Error("attempt 3 must not be retried")
+	}
+	if _, ok := b.Retry(1, &Response{Status: Status410}, nil); ok {
+		t.Error("Status 410 must not be retried")
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var ids []string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ids = append(ids, r.Header.Get("apns-id"))
+		attempt := len(ids)
+		mu.Unlock()
+
+		w.Header().Set("apns-id", r.Header.Get("apns-id"))
+		switch attempt {
+		case 1:
+			w.WriteHeader(Status503)
+			w.Write([]byte(`{"reason":"Shutdown"}`))
+		case 2:
+			w.WriteHeader(Status429)
+			w.Write([]byte(`{"reason":"TooManyRequests"}`))
+		}
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewClient(NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"), ts.Client())
+	client.RetryPolicy = &Backoff{MinDelay: time.Millisecond}
+
+	n := &Notification{Host: ts.URL, Payload: `{}`}
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != Status200 {
+		t.Errorf("res.Status: %v; want: %v", res.Status, Status200)
+	}
+	if n.ID != "" {
+		t.Error("notification must not be modified")
+	}
+	if len(ids) != 3 {
+		t.Fatalf("attempts: %v; want: 3", len(ids))
+	}
+	if ids[0] == "" || ids[0] != ids[1] || ids[1] != ids[2] {
+		t.Errorf("apns-id must be the same across attempts: %v", ids)
+	}
+	if res.ID != ids[0] {
+		t.Errorf("res.ID: %v; want: %v", res.ID, ids[0])
+	}
+}
+
+func TestClientRetryDeadline(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	attempts := 0
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		attempts++
+		mu.Unlock()
+		w.WriteHeader(Status500)
+		w.Write([]byte(`{"reason":"InternalServerError"}`))
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewClient(NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"), ts.Client())
+	client.RetryPolicy = &Backoff{MaxAttempts: 10, MinDelay: time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	res, err := client.PushWithContext(ctx, &Notification{Host: ts.URL, Payload: `{}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if time.Since(start) > 400*time.Millisecond {
+		t.Error("client must not wait past context deadline")
+	}
+	if res.Status != Status500 {
+		t.Errorf("res.Status: %v; want: %v", res.Status, Status500)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts: %v; want: 1", attempts)
+	}
+}
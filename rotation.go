@@ -0,0 +1,98 @@
+package apns
+
+import "sync"
+
+// TokenProvider provides tokens signing requests of Client,
+// for example, to rotate authentication token signing keys without downtime.
+type TokenProvider interface {
+	// Token returns token for signing the next request.
+	Token() *Token
+
+	// Invalid is invoked with token after APNs rejected its bearer
+	// with InvalidProviderToken, for example, after the key was revoked.
+	// Reports whether the request can be sent again with another token.
+	Invalid(token *Token) bool
+}
+
+// RotatingToken is TokenProvider holding primary and secondary tokens
+// signed by different keys. Requests are signed by the primary token,
+// it is switched to the secondary one when APNs rejects it as invalid,
+// unless the secondary one was rejected too.
+// It is safe for concurrent use.
+type RotatingToken struct {
+	mu        sync.RWMutex
+	primary   *Token
+	secondary *Token
+	rejected  map[*Token]bool
+}
+
+// NewRotatingToken returns RotatingToken with primary and secondary tokens,
+// pass nil for secondary to have no fallback.
+func NewRotatingToken(primary, secondary *Token) *RotatingToken {
+	return &RotatingToken{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+// Token returns the primary token.
+func (r *RotatingToken) Token() *Token {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.primary
+}
+
+// Primary returns the primary token.
+func (r *RotatingToken) Primary() *Token {
+	return r.Token()
+}
+
+// Secondary returns the secondary token.
+func (r *RotatingToken) Secondary() *Token {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.secondary
+}
+
+// Rotate makes token primary and the current primary token secondary,
+// for example, after a new key was created in the developer account.
+// Tokens rejected by APNs before are not switched to until the next Rotate,
+// except token itself.
+func (r *RotatingToken) Rotate(token *Token) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.primary, r.secondary = token, r.primary
+	delete(r.rejected, token)
+	for t := range r.rejected {
+		if t != r.secondary {
+			delete(r.rejected, t)
+		}
+	}
+}
+
+// Switch swaps the primary and secondary tokens if the secondary one exists.
+func (r *RotatingToken) Switch() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.secondary != nil {
+		r.primary, r.secondary = r.secondary, r.primary
+	}
+}
+
+// Invalid marks token as rejected and switches to the secondary token
+// if token is primary and the secondary one was not rejected.
+// Reports whether the primary token differs from token and was not rejected after that,
+// so the request can be sent again.
+// When both tokens are rejected, requests fail without switching them back and forth.
+func (r *RotatingToken) Invalid(token *Token) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.rejected == nil {
+		r.rejected = make(map[*Token]bool)
+	}
+	r.rejected[token] = true
+	if r.primary == token && r.secondary != nil && !r.rejected[r.secondary] {
+		r.primary, r.secondary = r.secondary, r.primary
+	}
+	return r.primary != nil && r.primary != token && !r.rejected[r.primary]
+}
@@ -0,0 +1,177 @@
+package apns
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRotatingToken(t *testing.T) {
+	primary := &Token{KeyID: "PRIMARY001"}
+	secondary := &Token{KeyID: "SECONDARY1"}
+	r := NewRotatingToken(primary, secondary)
+
+	if r.Token() != primary {
+		t.Errorf("Token: %v; want: %v", r.Token().KeyID, primary.KeyID)
+	}
+
+	if !r.Invalid(primary) {
+		t.Error("Invalid(primary) must report another token")
+	}
+	if r.Primary() != secondary || r.Secondary() != primary {
+		t.Errorf("tokens: %v, %v; want: %v, %v", r.Primary().KeyID, r.Secondary().KeyID, secondary.KeyID, primary.KeyID)
+	}
+
+	// Already switched by a concurrent request.
+	if !r.Invalid(primary) {
+		t.Error("Invalid(old primary) must report another token")
+	}
+	if r.Primary() != secondary {
+		t.Errorf("Primary: %v; want: %v", r.Primary().KeyID, secondary.KeyID)
+	}
+
+	rotated := &Token{KeyID: "ROTATED001"}
+	r.Rotate(rotated)
+	if r.Primary() != rotated || r.Secondary() != secondary {
+		t.Errorf("tokens: %v, %v; want: %v, %v", r.Primary().KeyID, r.Secondary().KeyID, rotated.KeyID, secondary.KeyID)
+	}
+
+	r.Switch()
+	if r.Primary() != secondary || r.Secondary() != rotated {
+		t.Errorf("tokens: %v, %v; want: %v, %v", r.Primary().KeyID, r.Secondary().KeyID, secondary.KeyID, rotated.KeyID)
+	}
+}
+
+func TestRotatingTokenWithoutSecondary(t *testing.T) {
+	primary := &Token{KeyID: "PRIMARY001"}
+	r := NewRotatingToken(primary, nil)
+
+	if r.Invalid(primary) {
+		t.Error("Invalid must not report another token")
+	}
+	r.Switch()
+	if r.Primary() != primary {
+		t.Errorf("Primary: %v; want: %v", r.Primary().KeyID, primary.KeyID)
+	}
+}
+
+func TestRotatingTokenBothInvalid(t *testing.T) {
+	primary := &Token{KeyID: "PRIMARY001"}
+	secondary := &Token{KeyID: "SECONDARY1"}
+	r := NewRotatingToken(primary, secondary)
+
+	if !r.Invalid(primary) {
+		t.Error("Invalid(primary) must report another token")
+	}
+	if r.Invalid(secondary) {
+		t.Error("Invalid(secondary) must not report rejected token")
+	}
+	// Concurrent requests signed by any of rejected tokens don't switch them.
+	for _, token := range []*Token{primary, secondary, primary} {
+		if r.Invalid(token) {
+			t.Errorf("Invalid(%v) must not report rejected token", token.KeyID)
+		}
+		if r.Primary() != secondary || r.Secondary() != primary {
+			t.Errorf("tokens: %v, %v; want: %v, %v", r.Primary().KeyID, r.Secondary().KeyID, secondary.KeyID, primary.KeyID)
+		}
+	}
+
+	// A new token is switched to.
+	rotated := &Token{KeyID: "ROTATED001"}
+	r.Rotate(rotated)
+	if !r.Invalid(secondary) {
+		t.Error("Invalid(secondary) must report rotated token")
+	}
+	if r.Invalid(rotated) {
+		t.Error("Invalid(rotated) must not report rejected secondary token")
+	}
+	if r.Primary() != rotated {
+		t.Errorf("Primary: %v; want: %v", r.Primary().KeyID, rotated.KeyID)
+	}
+}
+
+func TestClientPushTokenProvider(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var kids []string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer := strings.TrimPrefix(r.Header.Get("authorization"), "bearer ")
+		header, err := base64.RawURLEncoding.DecodeString(strings.Split(bearer, ".")[0])
+		if err != nil {
+			t.Error(err)
+		}
+		var h struct{ Kid string }
+		json.Unmarshal(header, &h)
+
+		mu.Lock()
+		kids = append(kids, h.Kid)
+		mu.Unlock()
+
+		if h.Kid == "REVOKED001" {
+			w.WriteHeader(Status403)
+			w.Write([]byte(`{"reason":"InvalidProviderToken"}`))
+		}
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	provider := NewRotatingToken(
+		NewToken(key, "REVOKED001", "SUPERTEEM1"),
+		NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"),
+	)
+	client := &Client{TokenProvider: provider, HTTPClient: ts.Client()}
+	n := &Notification{Host: ts.URL, Payload: `{}`}
+
+	res, err := client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status != Status200 {
+		t.Errorf("res.Status: %v; want: %v", res.Status, Status200)
+	}
+	if res.KeyID != "5MDQ4KLTY7" {
+		t.Errorf("res.KeyID: %v; want: %v", res.KeyID, "5MDQ4KLTY7")
+	}
+	if len(kids) != 2 || kids[0] != "REVOKED001" || kids[1] != "5MDQ4KLTY7" {
+		t.Errorf("kids: %v; want: [REVOKED001 5MDQ4KLTY7]", kids)
+	}
+
+	// Both keys are revoked, the request is resent only once.
+	provider.Rotate(NewToken(key, "REVOKED001", "SUPERTEEM1"))
+	provider.Rotate(NewToken(key, "REVOKED001", "SUPERTEEM1"))
+	kids = nil
+	res, err = client.Push(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Reason != ReasonInvalidProviderToken {
+		t.Errorf("res.Reason: %v; want: %v", res.Reason, ReasonInvalidProviderToken)
+	}
+	if len(kids) != 2 {
+		t.Errorf("requests: %v; want: 2", len(kids))
+	}
+
+	// Both tokens were rejected, they are not switched anymore.
+	primary := provider.Primary()
+	for i := 0; i < 3; i++ {
+		kids = nil
+		if _, err := client.Push(n); err != nil {
+			t.Fatal(err)
+		}
+		if len(kids) != 1 {
+			t.Errorf("requests: %v; want: 1", len(kids))
+		}
+	}
+	if provider.Primary() != primary {
+		t.Error("rejected tokens must not be switched")
+	}
+}
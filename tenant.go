@@ -0,0 +1,248 @@
+package apns
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrTenantTopicEmpty = errors.New("tenant: topic is empty")
+	ErrTenantNotFound   = errors.New("tenant: not found")
+)
+
+// Idle connections of TenantClient are closed after this timeout,
+// the same as of http.DefaultTransport.
+const tenantIdleConnTimeout = 90 * time.Second
+
+// Tenant is an app served by TenantClient.
+type Tenant struct {
+	// The bundle ID of the app.
+	// Notifications with topics having the push type suffix,
+	// for example, .voip or .complication, are sent by the tenant too.
+	BundleID string
+
+	// Token of the developer team of the app.
+	Token *Token
+
+	// Provider of tokens used instead of Token if it is set, see Client.TokenProvider.
+	TokenProvider TokenProvider
+
+	// HostDevelopment, HostProduction or their 2197 port variants
+	// used for notifications without Host.
+	// If Host field omitted HostProduction will be used.
+	Host string
+}
+
+// TenantClient is a token-based client sending notifications of many apps
+// of one or several developer teams. Notifications are routed to tenants by their topics.
+// Requests to the same tenant host share one HTTP/2 client,
+// requests to hosts set by notifications and used by no tenant share another one.
+// Tenants can be added and removed while notifications are sent.
+type TenantClient struct {
+	// If HTTPClient is set, it is used for all hosts,
+	// otherwise HTTP/2 client is created for each host.
+	HTTPClient *http.Client
+
+	// See Client.RetryPolicy.
+	RetryPolicy RetryPolicy
+
+	// See Client.DeviceTokenStore.
+	DeviceTokenStore DeviceTokenStore
+
+	// See Client.Validate.
+	Validate bool
+
+	mu      sync.RWMutex
+	tenants map[string]*Tenant
+	clients map[string]*http.Client // by tenant hosts
+	other   *http.Client            // for hosts of no tenant
+}
+
+// NewTenantClient creates client with tenants.
+func NewTenantClient(tenants ...*Tenant) *TenantClient {
+	c := &TenantClient{}
+	for _, t := range tenants {
+		c.AddTenant(t)
+	}
+	return c
+}
+
+// AddTenant adds tenant or replaces the tenant with the same BundleID.
+// If the replaced tenant has another host, idle connections
+// to the host no other tenant uses are closed.
+func (c *TenantClient) AddTenant(t *Tenant) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tenants == nil {
+		c.tenants = make(map[string]*Tenant)
+	}
+	old, ok := c.tenants[t.BundleID]
+	c.tenants[t.BundleID] = t
+	if ok {
+		c.releaseHost(tenantHost(old))
+	}
+}
+
+// RemoveTenant removes the tenant with bundleID.
+// Idle connections to the host no other tenant uses are closed.
+func (c *TenantClient) RemoveTenant(bundleID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t, ok := c.tenants[bundleID]
+	if !ok {
+		return
+	}
+	delete(c.tenants, bundleID)
+	c.releaseHost(tenantHost(t))
+}
+
+// releaseHost drops the client of host if no tenant uses host
+// and closes its idle connections. c.mu must be locked.
+func (c *TenantClient) releaseHost(host string) {
+	if c.usesHost(host) {
+		return
+	}
+	if client, ok := c.clients[host]; ok {
+		client.CloseIdleConnections()
+		delete(c.clients, host)
+	}
+}
+
+// usesHost reports whether some tenant uses host. c.mu must be locked.
+func (c *TenantClient) usesHost(host string) bool {
+	for _, t := range c.tenants {
+		if tenantHost(t) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// Tenant returns the tenant for topic of notification.
+// Returns nil if there is no such tenant.
+func (c *TenantClient) Tenant(topic string) *Tenant {
+	n := Notification{Topic: topic}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if t, ok := c.tenants[topic]; ok {
+		return t
+	}
+	return c.tenants[n.BundleID()]
+}
+
+// Tenants returns all tenants.
+func (c *TenantClient) Tenants() []*Tenant {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	tenants := make([]*Tenant, 0, len(c.tenants))
+	for _, t := range c.tenants {
+		tenants = append(tenants, t)
+	}
+	return tenants
+}
+
+// Push sends remote notification by the tenant of its topic.
+func (c *TenantClient) Push(n *Notification) (*Response, error) {
+	return c.PushWithContext(context.Background(), n)
+}
+
+// PushWithContext sends remote notification by the tenant of its topic with context.
+// Returns ErrTenantTopicEmpty for notification without Topic
+// and ErrTenantNotFound if there is no tenant for the topic.
+// See Client.PushWithContext.
+func (c *TenantClient) PushWithContext(ctx context.Context, n *Notification) (*Response, error) {
+	client, n, err := c.client(n)
+	if err != nil {
+		return nil, err
+	}
+	return client.PushWithContext(ctx, n)
+}
+
+// PushAll sends notifications received from ns concurrently by their tenants,
+// see Client.PushAll.
+func (c *TenantClient) PushAll(ctx context.Context, ns <-chan *Notification, workers int) <-chan Result {
+	return pushAll(ctx, ns, workers, c.PushWithContext)
+}
+
+// client returns client of the tenant of n
+// and n with Host of the tenant if n has no Host.
+func (c *TenantClient) client(n *Notification) (*Client, *Notification, error) {
+	if n == nil {
+		return nil, nil, ErrClientNotificationNil
+	}
+	if n.Topic == "" {
+		return nil, nil, ErrTenantTopicEmpty
+	}
+	t := c.Tenant(n.Topic)
+	if t == nil {
+		return nil, nil, ErrTenantNotFound
+	}
+
+	if n.Host == "" {
+		withHost := *n
+		withHost.Host = tenantHost(t)
+		n = &withHost
+	}
+
+	return &Client{
+		Token:            t.Token,
+		TokenProvider:    t.TokenProvider,
+		HTTPClient:       c.httpClient(n.Host),
+		RetryPolicy:      c.RetryPolicy,
+		DeviceTokenStore: c.DeviceTokenStore,
+		Validate:         c.Validate,
+	}, n, nil
+}
+
+// httpClient returns HTTP/2 client shared by requests to host.
+// Hosts of no tenant share one client, their idle connections are closed by timeout.
+func (c *TenantClient) httpClient(host string) *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	c.mu.RLock()
+	client, ok := c.clients[host]
+	c.mu.RUnlock()
+	if ok {
+		return client
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if client, ok := c.clients[host]; ok {
+		return client
+	}
+	if !c.usesHost(host) {
+		if c.other == nil {
+			c.other = newTenantHTTPClient()
+		}
+		return c.other
+	}
+	if c.clients == nil {
+		c.clients = make(map[string]*http.Client)
+	}
+	client = newTenantHTTPClient()
+	c.clients[host] = client
+	return client
+}
+
+func newTenantHTTPClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:             http.ProxyFromEnvironment,
+			ForceAttemptHTTP2: true,
+			IdleConnTimeout:   tenantIdleConnTimeout,
+		},
+	}
+}
+
+func tenantHost(t *Tenant) string {
+	if t.Host == "" {
+		return HostProduction
+	}
+	return t.Host
+}
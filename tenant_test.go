@@ -0,0 +1,171 @@
+package apns
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestTenantClientPush(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var kids []string
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer := strings.TrimPrefix(r.Header.Get("authorization"), "bearer ")
+		header, err := base64.RawURLEncoding.DecodeString(strings.Split(bearer, ".")[0])
+		if err != nil {
+			t.Error(err)
+		}
+		var h struct{ Kid string }
+		json.Unmarshal(header, &h)
+
+		mu.Lock()
+		kids = append(kids, h.Kid)
+		mu.Unlock()
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewTenantClient(
+		&Tenant{
+			BundleID: "com.example.app",
+			Token:    NewToken(key, "KEYTEAM001", "TEAM000001"),
+			Host:     ts.URL,
+		},
+		&Tenant{
+			BundleID: "com.example.other",
+			Token:    NewToken(key, "KEYTEAM002", "TEAM000002"),
+			Host:     ts.URL,
+		},
+	)
+	client.HTTPClient = ts.Client()
+
+	tests := []struct {
+		topic string
+		kid   string
+	}{
+		{"com.example.app", "KEYTEAM001"},
+		{"com.example.app.voip", "KEYTEAM001"},
+		{"com.example.other", "KEYTEAM002"},
+		{"com.example.other.complication", "KEYTEAM002"},
+	}
+	for _, test := range tests {
+		kids = nil
+		n := &Notification{Topic: test.topic, Payload: `{}`}
+		res, err := client.Push(n)
+		if err != nil {
+			t.Fatalf("%v: %v", test.topic, err)
+		}
+		if res.Status != Status200 {
+			t.Errorf("%v: res.Status: %v; want: %v", test.topic, res.Status, Status200)
+		}
+		if res.KeyID != test.kid {
+			t.Errorf("%v: res.KeyID: %v; want: %v", test.topic, res.KeyID, test.kid)
+		}
+		if len(kids) != 1 || kids[0] != test.kid {
+			t.Errorf("%v: kids: %v; want: [%v]", test.topic, kids, test.kid)
+		}
+		if n.Host != "" {
+			t.Errorf("%v: n.Host: %v; want empty", test.topic, n.Host)
+		}
+	}
+
+	_, err = client.Push(&Notification{Payload: `{}`})
+	if err != ErrTenantTopicEmpty {
+		t.Errorf("err: %v; want: %v", err, ErrTenantTopicEmpty)
+	}
+
+	_, err = client.Push(&Notification{Topic: "com.example.unknown", Payload: `{}`})
+	if err != ErrTenantNotFound {
+		t.Errorf("err: %v; want: %v", err, ErrTenantNotFound)
+	}
+
+	client.RemoveTenant("com.example.other")
+	_, err = client.Push(&Notification{Topic: "com.example.other", Payload: `{}`})
+	if err != ErrTenantNotFound {
+		t.Errorf("removed tenant: err: %v; want: %v", err, ErrTenantNotFound)
+	}
+	if len(client.Tenants()) != 1 {
+		t.Errorf("len(Tenants): %v; want: 1", len(client.Tenants()))
+	}
+}
+
+func TestTenantClientHTTPClient(t *testing.T) {
+	client := NewTenantClient(
+		&Tenant{BundleID: "com.example.app"},
+		&Tenant{BundleID: "com.example.other"},
+		&Tenant{BundleID: "com.example.dev", Host: HostDevelopment},
+	)
+
+	app, _, err := client.client(&Notification{Topic: "com.example.app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, n, err := client.client(&Notification{Topic: "com.example.other"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Host != HostProduction {
+		t.Errorf("n.Host: %v; want: %v", n.Host, HostProduction)
+	}
+	dev, n, err := client.client(&Notification{Topic: "com.example.dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Host != HostDevelopment {
+		t.Errorf("n.Host: %v; want: %v", n.Host, HostDevelopment)
+	}
+
+	if app.HTTPClient != other.HTTPClient {
+		t.Error("tenants with the same host must share HTTP client")
+	}
+	if app.HTTPClient == dev.HTTPClient {
+		t.Error("tenants with different hosts must not share HTTP client")
+	}
+
+	client.RemoveTenant("com.example.dev")
+	if _, ok := client.clients[HostDevelopment]; ok {
+		t.Error("HTTP client of unused host must be removed")
+	}
+	client.RemoveTenant("com.example.app")
+	if _, ok := client.clients[HostProduction]; !ok {
+		t.Error("HTTP client of used host must not be removed")
+	}
+
+	// Replacing the tenant with another host releases the previous host.
+	client.AddTenant(&Tenant{BundleID: "com.example.other", Host: HostDevelopmentPort2197})
+	if _, _, err := client.client(&Notification{Topic: "com.example.other"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := client.clients[HostProduction]; ok {
+		t.Error("HTTP client of replaced host must be removed")
+	}
+	if _, ok := client.clients[HostDevelopmentPort2197]; !ok {
+		t.Error("HTTP client of new host must be created")
+	}
+
+	// Hosts of notifications used by no tenant share one client and are not kept.
+	custom, _, err := client.client(&Notification{Topic: "com.example.other", Host: "https://localhost:2197"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom2, _, err := client.client(&Notification{Topic: "com.example.other", Host: "https://localhost:2198"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if custom.HTTPClient != custom2.HTTPClient || custom.HTTPClient != client.other {
+		t.Error("hosts of no tenant must share HTTP client")
+	}
+	if len(client.clients) != 1 {
+		t.Errorf("len(client.clients): %v; want: 1", len(client.clients))
+	}
+}
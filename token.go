@@ -1,16 +1,21 @@
 package apns
 
 import (
+	"crypto"
 	"crypto/ecdsa"
 	"errors"
 	"net/http"
 	"sync"
-	"time"
 )
 
 // See https://developer.apple.com/documentation/usernotifications/setting_up_a_remote_notification_server/establishing_a_token-based_connection_to_apns.
 
-var ErrTokenKeyNil = errors.New("token: key is nil")
+var (
+	ErrTokenKeyNil = errors.New("token: key is nil")
+
+	// Bearer can't be regenerated earlier than TokenMinRefreshInterval after IssuedAt.
+	ErrTokenRefreshTooSoon = errors.New("token: refresh too soon")
+)
 
 // For security, APNs requires you to refresh your token regularly.
 // Refresh your token no more than once every 20 minutes
@@ -21,6 +26,14 @@ var ErrTokenKeyNil = errors.New("token: key is nil")
 // more than once every 20 minutes.
 const TokenRefreshInterval = 2400 // 40 minutes
 
+// APNs returns TooManyProviderTokenUpdates
+// if tokens are recreated more than once every 20 minutes.
+const TokenMinRefreshInterval = 1200 // 20 minutes
+
+// APNs returns ExpiredProviderToken
+// if the token contains a timestamp that is more than one hour old.
+const TokenMaxAge = 3600 // 60 minutes
+
 // Token represents JSON Token used for token-based connection to APNs.
 type Token struct {
 	sync.Mutex
@@ -29,6 +42,10 @@ type Token struct {
 	// Use AuthKeyFromFile or AuthKeyFromBytes.
 	Key *ecdsa.PrivateKey
 
+	// Signer with ECDSA P-256 public key used instead of Key if Key is nil,
+	// for example, for the key held in KMS, Vault transit or PKCS #11 HSM.
+	Signer crypto.Signer
+
 	// The 10-character Key ID you obtained from your developer account
 	// with an authentication token signing key.
 	KeyID string
@@ -46,6 +63,14 @@ type Token struct {
 
 	// Generated JWT Token for APNs request authorization at IssuedAt time.
 	Bearer string
+
+	// Cache sharing Bearer and IssuedAt with tokens of other processes, nil disables sharing.
+	// If Cache is set, expired Bearer is taken from the cache
+	// and generated only if the cached one is expired too.
+	Cache BearerCache
+
+	// Clock for IssuedAt and expiration of Bearer, nil means the system time.
+	Clock Clock
 }
 
 // NewToken returns Token with key, keyID, teamID with default TokenRefreshInterval.
@@ -59,29 +84,75 @@ func NewToken(key *ecdsa.PrivateKey, keyID, teamID string) *Token {
 	}
 }
 
+// NewTokenWithSigner returns Token with signer, keyID, teamID with default TokenRefreshInterval.
+func NewTokenWithSigner(signer crypto.Signer, keyID, teamID string) *Token {
+	return &Token{
+		Signer: signer,
+		KeyID:  keyID,
+		TeamID: teamID,
+	}
+}
+
 func (t *Token) Expired() bool {
 	if t.RefreshInterval > 0 {
-		return time.Now().Unix() > t.IssuedAt+t.RefreshInterval
+		return now(t.Clock).Unix() > t.IssuedAt+t.RefreshInterval
 	} else {
-		return time.Now().Unix() > t.IssuedAt+TokenRefreshInterval
+		return now(t.Clock).Unix() > t.IssuedAt+TokenRefreshInterval
 	}
 }
 
 func (t *Token) GenerateIfExpired() (string, error) {
 	t.Lock()
 	defer t.Unlock()
+	if !t.Expired() {
+		return t.Bearer, nil
+	}
+	if t.Cache != nil {
+		return t.cached(t.generateIfExpired)
+	}
+	return t.Generate()
+}
+
+func (t *Token) generateIfExpired() (string, error) {
 	if t.Expired() {
 		return t.Generate()
 	}
 	return t.Bearer, nil
 }
 
+// cached calls generate with Bearer and IssuedAt updated from Cache
+// and caches them after that.
+func (t *Token) cached(generate func() (string, error)) (string, error) {
+	var bearer string
+	err := t.Cache.Update(t.KeyID, t.TeamID, func(cachedBearer string, issuedAt int64) (string, int64, error) {
+		if cachedBearer != "" && issuedAt > t.IssuedAt {
+			t.Bearer = cachedBearer
+			t.IssuedAt = issuedAt
+		}
+		var err error
+		bearer, err = generate()
+		if err != nil {
+			return "", 0, err
+		}
+		return t.Bearer, t.IssuedAt, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return bearer, nil
+}
+
 func (t *Token) Generate() (string, error) {
-	if t.Key == nil {
+	var signer crypto.Signer
+	if t.Key != nil {
+		signer = t.Key
+	} else if t.Signer != nil {
+		signer = t.Signer
+	} else {
 		return "", ErrTokenKeyNil
 	}
-	issuedAt := time.Now().Unix()
-	bearer, err := GenerateBearer(t.Key, t.KeyID, t.TeamID, issuedAt)
+	issuedAt := now(t.Clock).Unix()
+	bearer, err := GenerateBearerWithSigner(signer, t.KeyID, t.TeamID, issuedAt)
 	if err != nil {
 		return "", err
 	}
@@ -90,6 +161,34 @@ func (t *Token) Generate() (string, error) {
 	return bearer, nil
 }
 
+// Refresh regenerates Bearer after APNs rejected bearer as expired,
+// for example, after a clock jump or a resume of suspended process.
+// If Bearer differs from bearer and is not expired,
+// it was already regenerated and returned as is.
+// Returns ErrTokenRefreshTooSoon if less than TokenMinRefreshInterval
+// passed since IssuedAt to avoid TooManyProviderTokenUpdates.
+// If Cache is set, Bearer regenerated by other process is taken from it.
+func (t *Token) Refresh(bearer string) (string, error) {
+	t.Lock()
+	defer t.Unlock()
+	if t.Cache != nil {
+		return t.cached(func() (string, error) {
+			return t.refresh(bearer)
+		})
+	}
+	return t.refresh(bearer)
+}
+
+func (t *Token) refresh(bearer string) (string, error) {
+	if t.Bearer != bearer && !t.Expired() {
+		return t.Bearer, nil
+	}
+	if now(t.Clock).Unix() < t.IssuedAt+TokenMinRefreshInterval {
+		return "", ErrTokenRefreshTooSoon
+	}
+	return t.Generate()
+}
+
 func (t *Token) SetAuthorization(h http.Header) error {
 	bearer, err := t.GenerateIfExpired()
 	if err != nil {
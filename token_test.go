@@ -38,6 +38,22 @@ func TestTokenKeyInvalid(t *testing.T) {
 	}
 }
 
+func TestTokenSigner(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := NewTokenWithSigner(derSigner{key}, "5MDQ4KLTY7", "SUPERTEEM1")
+	bearer, err := token.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bearer == "" || bearer != token.Bearer {
+		t.Errorf("bearer: %q; want: %q", bearer, token.Bearer)
+	}
+}
+
 func TestTokenExpired(t *testing.T) {
 	token := &Token{}
 	if !token.Expired() {
@@ -76,6 +92,48 @@ func TestTokenNotExpired(t *testing.T) {
 	}
 }
 
+func TestTokenClock(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2021, 8, 15, 12, 0, 0, 0, time.UTC)
+	token := NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1")
+	token.Clock = ClockFunc(func() time.Time { return now })
+
+	bearer, err := token.GenerateIfExpired()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.IssuedAt != now.Unix() {
+		t.Errorf("IssuedAt: %v; want: %v", token.IssuedAt, now.Unix())
+	}
+
+	now = now.Add(TokenRefreshInterval * time.Second)
+	if token.Expired() {
+		t.Error("token must be not expired")
+	}
+	now = now.Add(time.Second)
+	if !token.Expired() {
+		t.Error("token must be expired")
+	}
+
+	// APNs rejected the bearer, but it was issued less than 20 minutes ago.
+	now = time.Date(2021, 8, 15, 12, 0, 0, 0, time.UTC).Add((TokenMinRefreshInterval - 1) * time.Second)
+	if _, err := token.Refresh(bearer); err != ErrTokenRefreshTooSoon {
+		t.Errorf("err: %v; want: %v", err, ErrTokenRefreshTooSoon)
+	}
+	now = now.Add(time.Second)
+	refreshed, err := token.Refresh(bearer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed == bearer || token.IssuedAt != now.Unix() {
+		t.Errorf("bearer must be regenerated at %v: %v", now.Unix(), token.IssuedAt)
+	}
+}
+
 func TestTokenGenerateIfExpired(t *testing.T) {
 	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
 	if err != nil {
@@ -139,3 +197,38 @@ func TestSetBearer(t *testing.T) {
 		t.Errorf("invalid authorization header: %v", h.Get("authorization"))
 	}
 }
+
+func TestTokenRefresh(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token := NewToken(key, "5JZB9P77A7", "SUPERTEEM1")
+	old, err := token.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = token.Refresh(old)
+	if err != ErrTokenRefreshTooSoon {
+		t.Errorf("err: %v; want: ErrTokenRefreshTooSoon", err)
+	}
+
+	token.IssuedAt = time.Now().Add(-(TokenMinRefreshInterval + 1) * time.Second).Unix()
+	bearer, err := token.Refresh(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bearer == old || bearer != token.Bearer {
+		t.Error("bearer must be regenerated")
+	}
+
+	again, err := token.Refresh(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != bearer {
+		t.Error("bearer regenerated by another request must be reused")
+	}
+}
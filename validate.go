@@ -0,0 +1,117 @@
+package apns
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Length of device token in hexadecimal digits.
+const DeviceTokenLength = 64
+
+var uuidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// Topic suffixes required by push types.
+var topicSuffixes = map[string]string{
+	PushTypeVoIP:         ".voip",
+	PushTypeComplication: ".complication",
+	PushTypeFileprovider: ".pushkit.fileprovider",
+	PushTypeLiveActivity: ".push-type.liveactivity",
+}
+
+// MaxPayloadSize returns maximum size of the notification payload:
+// MaxVoIPPayloadSize for PushTypeVoIP and MaxPayloadSize for others.
+func (n *Notification) MaxPayloadSize() int {
+	if n.PushType == PushTypeVoIP {
+		return MaxVoIPPayloadSize
+	}
+	return MaxPayloadSize
+}
+
+// Validate checks the notification for errors APNs would reject it with.
+// Returned error matches the corresponding ErrReason* value with errors.Is,
+// for example, ErrReasonBadDeviceToken or ErrReasonPayloadTooLarge.
+// Topic is checked only when it is set, because it is optional
+// for certificate-based connection with a single topic.
+// Broadcast notification with ChannelID must have Topic and PushTypeLiveActivity.
+func (n *Notification) Validate() error {
+	if n.ChannelID != "" {
+		if n.Topic == "" {
+			return validationError("broadcast requires topic", ErrReasonMissingTopic)
+		}
+		if n.PushType != PushTypeLiveActivity {
+			return validationError("broadcast requires liveactivity push type", ErrReasonInvalidPushType)
+		}
+	} else if err := validateDeviceToken(n.DeviceToken); err != nil {
+		return err
+	}
+
+	if n.ID != "" && !uuidRegexp.MatchString(n.ID) {
+		return validationError("id is not canonical UUID", ErrReasonBadMessageId)
+	}
+
+	switch n.PushType {
+	case "", PushTypeAlert, PushTypeBackground, PushTypeVoIP, PushTypeComplication, PushTypeFileprovider, PushTypeMDM, PushTypeLiveActivity:
+	default:
+		return validationError("unknown push type "+strconv.Quote(n.PushType), ErrReasonInvalidPushType)
+	}
+	// Broadcast notification takes bundle ID with or without the suffix.
+	if suffix, ok := topicSuffixes[n.PushType]; ok && n.ChannelID == "" && n.Topic != "" && !strings.HasSuffix(n.Topic, suffix) {
+		return validationError(n.PushType+" push type requires topic with "+suffix+" suffix", ErrReasonBadTopic)
+	}
+
+	switch n.Priority {
+	case 0, PriorityPowerSaving, PriorityLow:
+	case PriorityHigh:
+		if n.PushType == PushTypeBackground {
+			return validationError("background push type requires priority 5", ErrReasonBadPriority)
+		}
+	default:
+		return validationError("invalid priority "+strconv.Itoa(n.Priority), ErrReasonBadPriority)
+	}
+
+	if n.Expiration != "" {
+		if e, err := strconv.ParseInt(n.Expiration, 10, 64); err != nil || e < 0 {
+			return validationError("expiration is not UNIX epoch in seconds", ErrReasonBadExpirationDate)
+		}
+	}
+
+	if len(n.CollapseID) > MaxCollapseIDSize {
+		return validationError("collapse id exceeds "+strconv.Itoa(MaxCollapseIDSize)+" bytes", ErrReasonBadCollapseId)
+	}
+
+	if n.Payload == nil {
+		return validationError("payload is empty", ErrReasonPayloadEmpty)
+	}
+	b, err := n.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	if len(b) == 0 {
+		return validationError("payload is empty", ErrReasonPayloadEmpty)
+	}
+	if max := n.MaxPayloadSize(); len(b) > max {
+		return validationError("payload size "+strconv.Itoa(len(b))+" exceeds "+strconv.Itoa(max)+" bytes", ErrReasonPayloadTooLarge)
+	}
+
+	return nil
+}
+
+func validateDeviceToken(deviceToken string) error {
+	if deviceToken == "" {
+		return validationError("device token is empty", ErrReasonMissingDeviceToken)
+	}
+	if len(deviceToken) != DeviceTokenLength {
+		return validationError("device token must be "+strconv.Itoa(DeviceTokenLength)+" hexadecimal digits", ErrReasonBadDeviceToken)
+	}
+	if _, err := hex.DecodeString(deviceToken); err != nil {
+		return validationError("device token is not hexadecimal", ErrReasonBadDeviceToken)
+	}
+	return nil
+}
+
+func validationError(msg string, reason *Error) error {
+	return fmt.Errorf("notification: %s: %w", msg, reason)
+}
@@ -0,0 +1,139 @@
+package apns
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func validNotification() *Notification {
+	return &Notification{
+		DeviceToken: "7c968c83f6fd6de5843c309150ed1a706bc64fcdc42310f66054c0271e67219e",
+		ID:          "EC1BF194-B3B2-424A-89A9-5A918A6E6B5D",
+		Topic:       "com.example.app",
+		PushType:    PushTypeAlert,
+		Expiration:  "0",
+		Priority:    PriorityHigh,
+		CollapseID:  "hello",
+		Payload: BuildPayload(&APS{
+			Alert: "Hello",
+		}, nil),
+	}
+}
+
+func TestNotificationValidate(t *testing.T) {
+	if err := validNotification().Validate(); err != nil {
+		t.Errorf("err: %v; want nil", err)
+	}
+
+	large := `{"a":"` + strings.Repeat("x", MaxPayloadSize) + `"}`
+
+	tests := []struct {
+		modify func(n *Notification)
+		want   error
+	}{
+		{func(n *Notification) { n.DeviceToken = "" }, ErrReasonMissingDeviceToken},
+		{func(n *Notification) { n.DeviceToken = "7c968c83" }, ErrReasonBadDeviceToken},
+		{func(n *Notification) { n.DeviceToken = strings.Repeat("x", DeviceTokenLength) }, ErrReasonBadDeviceToken},
+		{func(n *Notification) { n.ID = "EC1BF194B3B2424A89A95A918A6E6B5D" }, ErrReasonBadMessageId},
+		{func(n *Notification) { n.PushType = "unknown" }, ErrReasonInvalidPushType},
+		{func(n *Notification) { n.PushType = PushTypeVoIP }, ErrReasonBadTopic},
+		{func(n *Notification) { n.PushType = PushTypeComplication }, ErrReasonBadTopic},
+		{func(n *Notification) { n.PushType = PushTypeFileprovider }, ErrReasonBadTopic},
+		{func(n *Notification) { n.PushType = PushTypeLiveActivity }, ErrReasonBadTopic},
+		{func(n *Notification) { n.PushType = PushTypeBackground }, ErrReasonBadPriority},
+		{func(n *Notification) { n.Priority = 7 }, ErrReasonBadPriority},
+		{func(n *Notification) { n.Priority = PriorityPowerSaving }, nil},
+		{func(n *Notification) { n.SetDeliverOnce() }, nil},
+		{func(n *Notification) { n.Expiration = "-1" }, ErrReasonBadExpirationDate},
+		{func(n *Notification) { n.Expiration = "2021-08-15" }, ErrReasonBadExpirationDate},
+		{func(n *Notification) { n.CollapseID = strings.Repeat("x", MaxCollapseIDSize+1) }, ErrReasonBadCollapseId},
+		{func(n *Notification) { n.Payload = nil }, ErrReasonPayloadEmpty},
+		{func(n *Notification) { n.Payload = "" }, ErrReasonPayloadEmpty},
+		{func(n *Notification) { n.Payload = large }, ErrReasonPayloadTooLarge},
+		{func(n *Notification) {
+			n.PushType = PushTypeVoIP
+			n.Topic = "com.example.app.voip"
+			n.Payload = large
+		}, nil},
+		{func(n *Notification) {
+			n.PushType = PushTypeFileprovider
+			n.Topic = "com.example.app.pushkit.fileprovider"
+		}, nil},
+		{func(n *Notification) {
+			n.PushType = PushTypeLiveActivity
+			n.Topic = "com.example.app.push-type.liveactivity"
+		}, nil},
+		{func(n *Notification) { n.Topic = "" }, nil},
+		{func(n *Notification) {
+			n.DeviceToken = ""
+			n.ChannelID = "dHN0LXNyY2gtY2hubA=="
+		}, ErrReasonInvalidPushType},
+		{func(n *Notification) {
+			n.DeviceToken = ""
+			n.ChannelID = "dHN0LXNyY2gtY2hubA=="
+			n.PushType = PushTypeLiveActivity
+			n.Topic = ""
+		}, ErrReasonMissingTopic},
+		{func(n *Notification) {
+			n.DeviceToken = ""
+			n.ChannelID = "dHN0LXNyY2gtY2hubA=="
+			n.PushType = PushTypeLiveActivity
+		}, nil},
+	}
+
+	for i, tt := range tests {
+		n := validNotification()
+		tt.modify(n)
+		err := n.Validate()
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%v: err: %v; want nil", i, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%v: err: %v; want: %v", i, err, tt.want)
+		}
+	}
+}
+
+func TestClientValidate(t *testing.T) {
+	key, err := AuthKeyFromFile("testdata/AuthKey_5MDQ4KLTY7.p8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	client := NewClient(NewToken(key, "5MDQ4KLTY7", "SUPERTEEM1"), ts.Client())
+	client.Validate = true
+
+	n := validNotification()
+	n.Host = ts.URL
+	n.DeviceToken = "xyz"
+	_, err = client.Push(n)
+	if !errors.Is(err, ErrReasonBadDeviceToken) {
+		t.Errorf("err: %v; want: ErrReasonBadDeviceToken", err)
+	}
+	if requests != 0 {
+		t.Error("invalid notification must not be sent")
+	}
+
+	n = validNotification()
+	n.Host = ts.URL
+	if _, err = client.Push(n); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("requests: %v; want: 1", requests)
+	}
+}